@@ -5,11 +5,17 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/TerraFactory/tilegenerator/assets"
+	"github.com/TerraFactory/tilegenerator/database"
 	"github.com/TerraFactory/tilegenerator/listeners"
 	"github.com/TerraFactory/tilegenerator/settings"
+	"github.com/TerraFactory/tilegenerator/settings/styling"
+	"github.com/TerraFactory/tilegenerator/tiles"
 	"github.com/TerraFactory/tilegenerator/utils"
 
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 func setOutputFileForLog(folder string) error {
@@ -24,7 +30,7 @@ func setOutputFileForLog(folder string) error {
 
 	f, err := os.OpenFile(pathLogFile, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
 	if err != nil {
-		fmt.Println("Error opening file for: %v", err)
+		fmt.Printf("Error opening file for: %v\n", err)
 	}
 
 	log.SetOutput(f)
@@ -32,15 +38,74 @@ func setOutputFileForLog(folder string) error {
 	return nil
 }
 
+// runBenchmark prints query plans and timings for tiles listed as "z/x/y,z/x/y"
+func runBenchmark(conf *settings.Settings, tilesList string, situations string) error {
+	sources := database.NewSources(conf)
+	for _, t := range strings.Split(tilesList, ",") {
+		var x, y, z int
+		if _, err := fmt.Sscanf(strings.TrimSpace(t), "%d/%d/%d", &z, &x, &y); err != nil {
+			return fmt.Errorf("Wrong tile %s, expected z/x/y", t)
+		}
+		tile := tiles.NewTile(x, y, z)
+		tile.BoundingBox.AddMargin()
+		for _, db := range sources {
+			fmt.Printf("Source %s\n", db.Name)
+			if err := db.Benchmark(tile, situations, os.Stdout); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateStyles prints all problems of the styles directory and returns exit code of the command.
+// The directory from the configuration file is used if "directory" is empty.
+func validateStyles(confPath *string, directory string, checkHrefs bool) int {
+	conf, err := settings.GetSettings(confPath)
+	if directory == "" {
+		if err != nil {
+			fmt.Println(err.Error())
+			return 2
+		}
+		directory = conf.StylesDirectory
+	}
+	if err == nil {
+		if err := assets.LoadStores(conf.Assets); err != nil {
+			fmt.Println(err.Error())
+			return 2
+		}
+	}
+	errs := styling.Validate(directory, checkHrefs)
+	if _, err := tiles.LoadGraphicsFile(filepath.Join(directory, styling.GraphicsFile)); err != nil {
+		errs = append(errs, err)
+	}
+	for _, err := range errs {
+		fmt.Println(err.Error())
+	}
+	if styling.HasErrors(errs) {
+		fmt.Printf("%v problems found\n", len(errs))
+		return 1
+	}
+	fmt.Println("Styles are valid")
+	return 0
+}
+
 func main() {
 	var help = flag.Bool("h", false, "Display this message.")
 	var conf_path = flag.String("c", "./config.toml", "Absolute path to configuration file")
+	var benchmark = flag.String("benchmark", "", "Print query plans and timings for tiles, e.g. \"12/2475/1280,8/154/80\"")
+	var situations = flag.String("situations", "", "Situations ids used by benchmark, e.g. \"1,2\"")
+	var checkHrefs = flag.Bool("check-hrefs", true, "Request image hrefs while validating styles")
 	flag.Parse()
 	if *help {
+		fmt.Println("Usage: tilegenerator [flags]\n       tilegenerator [flags] validate-styles [directory]")
 		flag.PrintDefaults()
 		os.Exit(0)
 		return
 	}
+	if flag.Arg(0) == "validate-styles" {
+		os.Exit(validateStyles(conf_path, flag.Arg(1), *checkHrefs))
+	}
 	conf, err := settings.GetSettings(conf_path)
 
 	if err = setOutputFileForLog(conf.LogDirectory); err != nil {
@@ -52,5 +117,12 @@ func main() {
 		os.Exit(1)
 		return
 	}
+	if *benchmark != "" {
+		if err := runBenchmark(conf, *benchmark, *situations); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
 	listeners.StartApplication(conf)
 }
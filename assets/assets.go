@@ -0,0 +1,226 @@
+// Package assets loads images referenced by styles: remote URLs, local files and symbols of named asset stores.
+package assets
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/TerraFactory/tilegenerator/utils"
+)
+
+// Extensions tried for asset names without an extension, e.g. "asset://mil/airbase"
+var symbolExtensions = []string{".svg", ".png"}
+
+// Store is a named set of symbols read into memory from a directory or a zip archive
+type Store struct {
+	Name  string
+	files map[string][]byte
+}
+
+// LoadStore reads all files of the directory or the zip archive. Files are named by their paths inside of it.
+func LoadStore(name, path string) (*Store, error) {
+	store := Store{Name: name, files: map[string][]byte{}}
+	if utils.IsDirectory(path) {
+		err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			relative, _ := filepath.Rel(path, file)
+			store.files[filepath.ToSlash(relative)] = data
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &store, nil
+	}
+
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("Asset store %s should be a directory or a zip archive: %v", path, err)
+	}
+	defer archive.Close()
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		reader, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return nil, err
+		}
+		store.files[strings.TrimPrefix(file.Name, "./")] = data
+	}
+	return &store, nil
+}
+
+// Get returns content of the symbol. Extensions ".svg" and ".png" are tried for names without an extension.
+func (store *Store) Get(name string) ([]byte, bool) {
+	name = strings.TrimPrefix(name, "/")
+	if data, ok := store.files[name]; ok {
+		return data, true
+	}
+	if filepath.Ext(name) == "" {
+		for _, ext := range symbolExtensions {
+			if data, ok := store.files[name+ext]; ok {
+				return data, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Len returns count of files in the store
+func (store *Store) Len() int {
+	return len(store.files)
+}
+
+// Stores are asset stores available by "asset://<name>/" hrefs
+type Stores struct {
+	mutex  sync.RWMutex
+	stores map[string]*Store
+}
+
+// NewStores creates an empty set of stores
+func NewStores() *Stores {
+	return &Stores{stores: map[string]*Store{}}
+}
+
+// DefaultStores are used by Register, LoadStores, Load and LoadWith
+var DefaultStores = NewStores()
+
+// Register makes the store available by "asset://<name>/" hrefs. A store with the same name is replaced.
+func (stores *Stores) Register(store *Store) {
+	stores.mutex.Lock()
+	defer stores.mutex.Unlock()
+	stores.stores[store.Name] = store
+}
+
+// LoadAll loads and registers stores configured as name = path
+func (stores *Stores) LoadAll(paths map[string]string) error {
+	for name, path := range paths {
+		store, err := LoadStore(name, path)
+		if err != nil {
+			return err
+		}
+		stores.Register(store)
+	}
+	return nil
+}
+
+// LoadWith returns content of the image by href like Load, URLs are requested with "fetch"
+// and "asset://" hrefs are symbols of these stores
+func (stores *Stores) LoadWith(fetch func(url string) ([]byte, error), href string) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(href, "http://") || strings.HasPrefix(href, "https://"):
+		return fetch(href)
+	case strings.HasPrefix(href, "file://"):
+		return ioutil.ReadFile(strings.TrimPrefix(href, "file://"))
+	case strings.HasPrefix(href, "asset://"):
+		parts := strings.SplitN(strings.TrimPrefix(href, "asset://"), "/", 2)
+		stores.mutex.RLock()
+		store, ok := stores.stores[parts[0]]
+		stores.mutex.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("Asset store %s is not found", parts[0])
+		}
+		if len(parts) == 2 {
+			if data, ok := store.Get(parts[1]); ok {
+				return data, nil
+			}
+		}
+		return nil, fmt.Errorf("Symbol %s is not found", href)
+	case strings.Contains(href, "://"):
+		return nil, errors.New("Unsupported href scheme of " + href)
+	default:
+		return ioutil.ReadFile(href)
+	}
+}
+
+// Register makes the store available in DefaultStores
+func Register(store *Store) {
+	DefaultStores.Register(store)
+}
+
+// LoadStores loads and registers stores configured as name = path in DefaultStores
+func LoadStores(paths map[string]string) error {
+	return DefaultStores.LoadAll(paths)
+}
+
+// Load returns content of the image by href:
+//
+//	"http://..." and "https://..." are requested by the shared fetcher;
+//	"file:///path/symbol.png" and "/path/symbol.png" are local files;
+//	"asset://store/symbol.png" is a symbol from DefaultStores.
+func Load(href string) ([]byte, error) {
+	return DefaultStores.LoadWith(Fetch, href)
+}
+
+// Resolve returns href with a relative file path resolved against the directory.
+// URLs, absolute paths and templates are returned as is, templates are resolved by ResolveExpanded.
+func Resolve(href, directory string) string {
+	if href == "" || strings.Contains(href, ":") || filepath.IsAbs(href) || strings.Contains(href, "${") {
+		return href
+	}
+	return "file://" + filepath.Join(directory, href)
+}
+
+// ResolveExpanded returns href expanded from a template with object data. Values of objects are not trusted,
+// so only http(s) URLs, "asset://" hrefs and relative paths of files inside the directory are allowed.
+// Escaped paths are unescaped, absolute paths, ".." and other schemes are rejected.
+func ResolveExpanded(href, directory string) (string, error) {
+	switch {
+	case strings.HasPrefix(href, "http://") || strings.HasPrefix(href, "https://"):
+		return href, nil
+	case strings.HasPrefix(href, "asset://"):
+		name, err := url.PathUnescape(href)
+		if err != nil {
+			return "", fmt.Errorf("Wrong href %s: %v", href, err)
+		}
+		return name, nil
+	case strings.Contains(href, ":"):
+		return "", errors.New("Unsupported href scheme of " + href)
+	}
+	path, err := url.PathUnescape(href)
+	if err != nil {
+		return "", fmt.Errorf("Wrong href %s: %v", href, err)
+	}
+	if path == "" || filepath.IsAbs(path) || strings.HasPrefix(path, "/") || strings.HasPrefix(path, "\\") {
+		return "", errors.New("Absolute paths are not allowed in hrefs of objects: " + path)
+	}
+	for _, segment := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if segment == ".." {
+			return "", errors.New("Parent directories are not allowed in hrefs of objects: " + path)
+		}
+	}
+	return "file://" + filepath.Join(directory, path), nil
+}
+
+// ContentType detects MIME type of the image, e.g. "image/png" or "image/svg+xml"
+func ContentType(data []byte) string {
+	head := data
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	if bytes.Contains(head, []byte("<svg")) {
+		return "image/svg+xml"
+	}
+	return http.DetectContentType(data)
+}
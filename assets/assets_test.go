@@ -0,0 +1,83 @@
+package assets
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestLoad_ZipStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "assets")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	archivePath := filepath.Join(dir, "mil.zip")
+	file, err := os.Create(archivePath)
+	assert.Nil(t, err)
+	archive := zip.NewWriter(file)
+	w, _ := archive.Create("air/airbase.svg")
+	w.Write([]byte(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"></svg>`))
+	w, _ = archive.Create("tank.png")
+	w.Write(pngHeader)
+	assert.Nil(t, archive.Close())
+	file.Close()
+
+	assert.Nil(t, LoadStores(map[string]string{"mil": archivePath}))
+
+	data, err := Load("asset://mil/air/airbase")
+	assert.Nil(t, err)
+	assert.Equal(t, "image/svg+xml", ContentType(data))
+	data, err = Load("asset://mil/tank.png")
+	assert.Nil(t, err)
+	assert.Equal(t, "image/png", ContentType(data))
+
+	_, err = Load("asset://mil/unknown.png")
+	assert.NotNil(t, err)
+	_, err = Load("asset://unknown/tank.png")
+	assert.NotNil(t, err)
+}
+
+func TestLoad_Files(t *testing.T) {
+	dir, err := ioutil.TempDir("", "assets")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "home.png"), pngHeader, 0644))
+
+	href := Resolve("home.png", dir)
+	assert.Equal(t, "file://"+filepath.Join(dir, "home.png"), href)
+	data, err := Load(href)
+	assert.Nil(t, err)
+	assert.Equal(t, pngHeader, data)
+
+	assert.Equal(t, "https://example.com/a.png", Resolve("https://example.com/a.png", dir))
+	assert.Equal(t, "${prop.icon}", Resolve("${prop.icon}", dir))
+	assert.Equal(t, "symbols/${prop.icon}.svg", Resolve("symbols/${prop.icon}.svg", dir))
+}
+
+func TestResolveExpanded(t *testing.T) {
+	dir := "/styles"
+	href, err := ResolveExpanded("symbols/a%20b.svg", dir)
+	assert.Nil(t, err)
+	assert.Equal(t, "file:///styles/symbols/a b.svg", href)
+
+	href, err = ResolveExpanded("https://example.com/a.png", dir)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://example.com/a.png", href)
+
+	href, err = ResolveExpanded("asset://mil/Red%20square.svg", dir)
+	assert.Nil(t, err)
+	assert.Equal(t, "asset://mil/Red square.svg", href)
+
+	/* Values of objects can't read files outside of the styles directory */
+	for _, href := range []string{"/etc/passwd", "%2Fetc%2Fpasswd", "..%2F..%2Fconfig.toml", "../../config.toml",
+		"symbols/../../config.toml", "file:///etc/passwd", "file:%2F%2F%2Fetc%2Fpasswd", ""} {
+		_, err := ResolveExpanded(href, dir)
+		assert.NotNil(t, err, href)
+	}
+}
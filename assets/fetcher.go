@@ -0,0 +1,317 @@
+package assets
+
+import (
+	"container/list"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FetcherOptions configure a Fetcher. Zero values are replaced with defaults of DefaultFetcherOptions.
+// CacheDirectory enables the disk tier: fetched images survive restarts and are revalidated when they expire.
+type FetcherOptions struct {
+	CacheSize      int
+	CacheDirectory string
+	Timeout        time.Duration
+	MaxPerHost     int
+	TTL            time.Duration
+	FailureTTL     time.Duration
+}
+
+// DefaultFetcherOptions are used by Fetch
+var DefaultFetcherOptions = FetcherOptions{
+	CacheSize:  1000,
+	Timeout:    5 * time.Second,
+	MaxPerHost: 4,
+	TTL:        time.Hour,
+	FailureTTL: 30 * time.Second,
+}
+
+// fetched is a cached response. Failures are cached too, so an unreachable host is not requested for every object.
+type fetched struct {
+	URL          string
+	Data         []byte `json:"-"`
+	Err          string
+	Expires      time.Time
+	ETag         string
+	LastModified string
+}
+
+func (f *fetched) result() ([]byte, error) {
+	if f.Err != "" {
+		return nil, fmt.Errorf("%s", f.Err)
+	}
+	return f.Data, nil
+}
+
+// call is a request in progress, concurrent fetches of the same URL wait for it instead of requesting it again
+type call struct {
+	done   chan struct{}
+	result *fetched
+}
+
+// Fetcher requests images by URL and keeps them in a LRU cache. It is safe for concurrent use.
+// Cache-Control (max-age, no-cache, no-store) and Expires headers of responses define how long images are fresh,
+// TTL is used if there are no such headers. Expired images with ETag or Last-Modified are revalidated,
+// and stale images are returned if revalidation fails.
+type Fetcher struct {
+	options FetcherOptions
+	client  *http.Client
+
+	mutex sync.Mutex
+	items map[string]*list.Element
+	order *list.List
+	calls map[string]*call
+	hosts map[string]chan struct{}
+}
+
+// NewFetcher creates a fetcher with the options
+func NewFetcher(options FetcherOptions) *Fetcher {
+	if options.CacheSize <= 0 {
+		options.CacheSize = DefaultFetcherOptions.CacheSize
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultFetcherOptions.Timeout
+	}
+	if options.MaxPerHost <= 0 {
+		options.MaxPerHost = DefaultFetcherOptions.MaxPerHost
+	}
+	if options.TTL <= 0 {
+		options.TTL = DefaultFetcherOptions.TTL
+	}
+	if options.FailureTTL <= 0 {
+		options.FailureTTL = DefaultFetcherOptions.FailureTTL
+	}
+	if options.CacheDirectory != "" {
+		if err := os.MkdirAll(options.CacheDirectory, 0755); err != nil {
+			log.Printf("Can't create images cache directory %s: %v \n", options.CacheDirectory, err)
+			options.CacheDirectory = ""
+		}
+	}
+	return &Fetcher{
+		options: options,
+		client:  &http.Client{Timeout: options.Timeout},
+		items:   map[string]*list.Element{},
+		order:   list.New(),
+		calls:   map[string]*call{},
+		hosts:   map[string]chan struct{}{},
+	}
+}
+
+// DefaultFetcher is shared by Fetch, Load and renderers without their own fetchers
+var DefaultFetcher = NewFetcher(DefaultFetcherOptions)
+
+// Fetch returns the image by URL with the fetcher shared by Load. Renderers use their own fetchers.
+func Fetch(url string) ([]byte, error) {
+	return DefaultFetcher.Fetch(url)
+}
+
+// Fetch returns the image from the cache or requests it. Only one request of the URL is made at a time.
+func (f *Fetcher) Fetch(url string) ([]byte, error) {
+	f.mutex.Lock()
+	cached := f.get(url)
+	if cached != nil && time.Now().Before(cached.Expires) {
+		f.mutex.Unlock()
+		return cached.result()
+	}
+	if c, ok := f.calls[url]; ok {
+		f.mutex.Unlock()
+		<-c.done
+		return c.result.result()
+	}
+	c := &call{done: make(chan struct{})}
+	f.calls[url] = c
+	f.mutex.Unlock()
+
+	if cached == nil {
+		cached = f.readDisk(url)
+	}
+	if cached != nil && time.Now().Before(cached.Expires) {
+		c.result = cached
+	} else {
+		c.result = f.request(url, cached)
+	}
+
+	f.mutex.Lock()
+	f.put(c.result)
+	delete(f.calls, url)
+	f.mutex.Unlock()
+	close(c.done)
+	return c.result.result()
+}
+
+// get returns the cached response, the caller holds the mutex
+func (f *Fetcher) get(url string) *fetched {
+	if element, ok := f.items[url]; ok {
+		f.order.MoveToFront(element)
+		return element.Value.(*fetched)
+	}
+	return nil
+}
+
+// put caches the response and evicts the least recently used ones, the caller holds the mutex
+func (f *Fetcher) put(result *fetched) {
+	if element, ok := f.items[result.URL]; ok {
+		element.Value = result
+		f.order.MoveToFront(element)
+		return
+	}
+	f.items[result.URL] = f.order.PushFront(result)
+	for f.order.Len() > f.options.CacheSize {
+		back := f.order.Back()
+		f.order.Remove(back)
+		delete(f.items, back.Value.(*fetched).URL)
+	}
+}
+
+// acquire waits for a free slot of the host and returns a function releasing it
+func (f *Fetcher) acquire(rawURL string) func() {
+	host := rawURL
+	if parsed, err := url.Parse(rawURL); err == nil {
+		host = parsed.Host
+	}
+	f.mutex.Lock()
+	slots, ok := f.hosts[host]
+	if !ok {
+		slots = make(chan struct{}, f.options.MaxPerHost)
+		f.hosts[host] = slots
+	}
+	f.mutex.Unlock()
+
+	slots <- struct{}{}
+	return func() { <-slots }
+}
+
+// request fetches the URL. If there is a stale response with validators, it is revalidated.
+func (f *Fetcher) request(url string, stale *fetched) *fetched {
+	failure := func(format string, args ...interface{}) *fetched {
+		err := fmt.Sprintf(format, args...)
+		log.Printf("Can't fetch image %s: %s \n", url, err)
+		if stale != nil && stale.Err == "" {
+			// Stale image is better than nothing, it is requested again after FailureTTL
+			result := *stale
+			result.Expires = time.Now().Add(f.options.FailureTTL)
+			return &result
+		}
+		return &fetched{URL: url, Err: err, Expires: time.Now().Add(f.options.FailureTTL)}
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return failure("%v", err)
+	}
+	if stale != nil && stale.Err == "" {
+		if stale.ETag != "" {
+			req.Header.Set("If-None-Match", stale.ETag)
+		}
+		if stale.LastModified != "" {
+			req.Header.Set("If-Modified-Since", stale.LastModified)
+		}
+	}
+
+	release := f.acquire(url)
+	resp, err := f.client.Do(req)
+	if err != nil {
+		release()
+		return failure("%v", err)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	release()
+	if err != nil {
+		return failure("Can't read response: %v", err)
+	}
+
+	if resp.StatusCode == http.StatusNotModified && stale != nil && stale.Err == "" {
+		result := *stale
+		result.Expires = f.expires(resp.Header)
+		f.writeDisk(&result)
+		return &result
+	}
+	if resp.StatusCode != http.StatusOK {
+		return failure("HTTP %s", resp.Status)
+	}
+	result := fetched{
+		URL:          url,
+		Data:         data,
+		Expires:      f.expires(resp.Header),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	if !strings.Contains(resp.Header.Get("Cache-Control"), "no-store") {
+		f.writeDisk(&result)
+	}
+	return &result
+}
+
+// expires returns the moment until which the response is fresh
+func (f *Fetcher) expires(header http.Header) time.Time {
+	now := time.Now()
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-cache" || directive == "no-store":
+			return now
+		case strings.HasPrefix(directive, "max-age="):
+			if seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age=")); err == nil {
+				return now.Add(time.Duration(seconds) * time.Second)
+			}
+		}
+	}
+	if expires := header.Get("Expires"); expires != "" {
+		if t, err := http.ParseTime(expires); err == nil {
+			return t
+		}
+		return now
+	}
+	return now.Add(f.options.TTL)
+}
+
+// diskPath returns path of the cached image, its metadata is kept in the file with ".json" extension
+func (f *Fetcher) diskPath(url string) string {
+	hash := sha1.Sum([]byte(url))
+	return filepath.Join(f.options.CacheDirectory, hex.EncodeToString(hash[:]))
+}
+
+func (f *Fetcher) readDisk(url string) *fetched {
+	if f.options.CacheDirectory == "" {
+		return nil
+	}
+	path := f.diskPath(url)
+	meta, err := ioutil.ReadFile(path + ".json")
+	if err != nil {
+		return nil
+	}
+	result := fetched{}
+	if err := json.Unmarshal(meta, &result); err != nil || result.URL != url {
+		return nil
+	}
+	if result.Data, err = ioutil.ReadFile(path); err != nil {
+		return nil
+	}
+	return &result
+}
+
+func (f *Fetcher) writeDisk(result *fetched) {
+	if f.options.CacheDirectory == "" {
+		return
+	}
+	path := f.diskPath(result.URL)
+	meta, _ := json.Marshal(result)
+	if err := ioutil.WriteFile(path, result.Data, 0644); err != nil {
+		log.Printf("Can't write cached image %s: %v \n", path, err)
+		return
+	}
+	ioutil.WriteFile(path+".json", meta, 0644)
+}
@@ -0,0 +1,99 @@
+package assets
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetcher_CachesAndDeduplicates(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("image"))
+	}))
+	defer server.Close()
+
+	f := NewFetcher(FetcherOptions{})
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, err := f.Fetch(server.URL + "/a.png")
+			assert.Nil(t, err)
+			assert.Equal(t, "image", string(data))
+		}()
+	}
+	wg.Wait()
+	f.Fetch(server.URL + "/a.png")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestFetcher_CachesFailures(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	f := NewFetcher(FetcherOptions{})
+	_, err := f.Fetch(server.URL)
+	assert.NotNil(t, err)
+	_, err = f.Fetch(server.URL)
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestFetcher_RevalidatesExpired(t *testing.T) {
+	var requests, notModified int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Cache-Control", "max-age=0")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("image"))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "images")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	f := NewFetcher(FetcherOptions{CacheDirectory: dir})
+	f.Fetch(server.URL)
+	data, err := f.Fetch(server.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, "image", string(data))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&notModified))
+
+	// A new fetcher revalidates the image of the disk tier
+	data, err = NewFetcher(FetcherOptions{CacheDirectory: dir}).Fetch(server.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, "image", string(data))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&notModified))
+}
+
+func TestFetcher_Expires(t *testing.T) {
+	f := NewFetcher(FetcherOptions{TTL: time.Minute})
+	header := http.Header{}
+	assert.WithinDuration(t, time.Now().Add(time.Minute), f.expires(header), time.Second)
+	header.Set("Cache-Control", "public, max-age=600")
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), f.expires(header), time.Second)
+	header.Set("Cache-Control", "no-cache")
+	assert.WithinDuration(t, time.Now(), f.expires(header), time.Second)
+}
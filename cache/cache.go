@@ -0,0 +1,169 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/TerraFactory/tilegenerator/tiles"
+)
+
+// Key identifies a rendered tile. Query contains request parameters which affect rendering (e.g. situations).
+type Key struct {
+	Z, X, Y int
+	Query   string
+}
+
+type entry struct {
+	key     Key
+	content []byte
+	tags    []string
+}
+
+// TileCache is an in-memory LRU cache of rendered tiles. It is safe for concurrent use.
+type TileCache struct {
+	mutex      sync.Mutex
+	capacity   int
+	items      map[Key]*list.Element
+	order      *list.List
+	generation uint64
+}
+
+// NewTileCache creates a cache which keeps at most "capacity" tiles
+func NewTileCache(capacity int) *TileCache {
+	return &TileCache{
+		capacity: capacity,
+		items:    map[Key]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+// Get returns content of the cached tile
+func (c *TileCache) Get(key Key) ([]byte, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, ok := c.items[key]; ok {
+		c.order.MoveToFront(element)
+		return element.Value.(*entry).content, true
+	}
+	return nil, false
+}
+
+// Set puts content of the tile into the cache and evicts the least recently used tiles if the cache is full
+func (c *TileCache) Set(key Key, content []byte) {
+	c.SetTagged(key, content, nil)
+}
+
+// SetTagged puts content of the tile into the cache with tags, e.g. names of styles used to render the tile.
+// Tagged tiles can be removed by PurgeTags.
+func (c *TileCache) SetTagged(key Key, content []byte, tags []string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.set(key, content, tags)
+}
+
+// Generation returns a number which is changed by every purge. It is taken before reading data of a tile,
+// so the tile rendered from data older than a purge isn't cached by SetTaggedSince.
+func (c *TileCache) Generation() uint64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.generation
+}
+
+// SetTaggedSince puts the tile into the cache like SetTagged if nothing was purged since "generation"
+// was returned by Generation. It returns false if the tile is not cached.
+func (c *TileCache) SetTaggedSince(generation uint64, key Key, content []byte, tags []string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.generation != generation {
+		return false
+	}
+	c.set(key, content, tags)
+	return true
+}
+
+func (c *TileCache) set(key Key, content []byte, tags []string) {
+	if element, ok := c.items[key]; ok {
+		element.Value.(*entry).content = content
+		element.Value.(*entry).tags = tags
+		c.order.MoveToFront(element)
+		return
+	}
+	c.items[key] = c.order.PushFront(&entry{key: key, content: content, tags: tags})
+
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+}
+
+// PurgeRange removes all tiles of the range from the cache and returns count of removed tiles
+func (c *TileCache) PurgeRange(r tiles.TileRange) int {
+	return c.PurgeFunc(func(key Key) bool {
+		return r.Contains(key.Z, key.X, key.Y)
+	})
+}
+
+// PurgeFunc removes all tiles for which "match" returns true and returns count of removed tiles
+func (c *TileCache) PurgeFunc(match func(key Key) bool) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.generation++
+	count := 0
+	for key, element := range c.items {
+		if match(key) {
+			c.remove(element)
+			count++
+		}
+	}
+	return count
+}
+
+// PurgeTags removes all tiles having at least one of the tags and returns count of removed tiles
+func (c *TileCache) PurgeTags(tags []string) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.generation++
+	purged := map[string]bool{}
+	for _, tag := range tags {
+		purged[tag] = true
+	}
+	count := 0
+	for _, element := range c.items {
+		for _, tag := range element.Value.(*entry).tags {
+			if purged[tag] {
+				c.remove(element)
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
+
+// Purge removes all tiles from the cache
+func (c *TileCache) Purge() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.generation++
+	c.items = map[Key]*list.Element{}
+	c.order.Init()
+}
+
+// Len returns count of cached tiles
+func (c *TileCache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.order.Len()
+}
+
+func (c *TileCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.items, element.Value.(*entry).key)
+}
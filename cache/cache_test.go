@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/TerraFactory/tilegenerator/tiles"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTileCache_Eviction(t *testing.T) {
+	c := NewTileCache(2)
+	c.Set(Key{Z: 1, X: 0, Y: 0}, []byte("a"))
+	c.Set(Key{Z: 1, X: 1, Y: 0}, []byte("b"))
+	c.Get(Key{Z: 1, X: 0, Y: 0})
+	c.Set(Key{Z: 1, X: 1, Y: 1}, []byte("c"))
+
+	_, ok := c.Get(Key{Z: 1, X: 1, Y: 0})
+	assert.False(t, ok, "the least recently used tile should be evicted")
+	content, ok := c.Get(Key{Z: 1, X: 0, Y: 0})
+	assert.True(t, ok)
+	assert.Equal(t, []byte("a"), content)
+	assert.Equal(t, 2, c.Len())
+}
+
+func TestTileCache_PurgeRange(t *testing.T) {
+	c := NewTileCache(10)
+	c.Set(Key{Z: 2, X: 1, Y: 1, Query: "situations=1"}, []byte("a"))
+	c.Set(Key{Z: 2, X: 1, Y: 1}, []byte("b"))
+	c.Set(Key{Z: 2, X: 3, Y: 3}, []byte("c"))
+	c.Set(Key{Z: 3, X: 1, Y: 1}, []byte("d"))
+
+	removed := c.PurgeRange(tiles.TileRange{Z: 2, MinX: 0, MinY: 0, MaxX: 2, MaxY: 2})
+	assert.Equal(t, 2, removed)
+	assert.Equal(t, 2, c.Len())
+	_, ok := c.Get(Key{Z: 3, X: 1, Y: 1})
+	assert.True(t, ok, "tiles of other zoom levels should stay in the cache")
+}
+
+func TestTileCache_PurgeTags(t *testing.T) {
+	c := NewTileCache(10)
+	c.SetTagged(Key{Z: 1, X: 0, Y: 0}, []byte("a"), []string{"home", "route"})
+	c.SetTagged(Key{Z: 1, X: 1, Y: 0}, []byte("b"), []string{"mil/airbase"})
+	c.Set(Key{Z: 1, X: 1, Y: 1}, []byte("c"))
+
+	removed := c.PurgeTags([]string{"route", "unknown"})
+	assert.Equal(t, 1, removed)
+	_, ok := c.Get(Key{Z: 1, X: 0, Y: 0})
+	assert.False(t, ok)
+	assert.Equal(t, 2, c.Len())
+}
+
+func TestTileCache_SetTaggedSince(t *testing.T) {
+	c := NewTileCache(10)
+	generation := c.Generation()
+	assert.True(t, c.SetTaggedSince(generation, Key{Z: 1, X: 0, Y: 0}, []byte("a"), nil))
+
+	c.PurgeTags([]string{"unknown"})
+	assert.False(t, c.SetTaggedSince(generation, Key{Z: 1, X: 1, Y: 0}, []byte("b"), nil),
+		"tiles rendered before a purge should not be cached")
+	assert.Equal(t, 1, c.Len())
+	assert.True(t, c.SetTaggedSince(c.Generation(), Key{Z: 1, X: 1, Y: 0}, []byte("b"), nil))
+}
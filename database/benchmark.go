@@ -0,0 +1,47 @@
+package database
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/TerraFactory/tilegenerator/tiles"
+)
+
+// Benchmark runs queries of the tile with EXPLAIN ANALYZE and writes query plans and timings into "out".
+func (gdb *GeometryDB) Benchmark(tile *tiles.Tile, situationsIds string, out io.Writer) error {
+	queries := []struct {
+		name  string
+		query string
+	}{
+		{ObjectsLayer, gdb.geometriesQuery(tile, situationsIds, nil)},
+		{SpecialLayer, gdb.specialObjectsQuery(tile, situationsIds, nil)},
+	}
+
+	for _, q := range queries {
+		fmt.Fprintf(out, "Tile %v/%v/%v, %s:\n", tile.Z, tile.X, tile.Y, q.name)
+
+		start := time.Now()
+		objects, err := gdb.query(q.query)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "\t%v objects in %v\n", len(objects), time.Since(start))
+
+		rows, err := gdb.conn.Query("EXPLAIN (ANALYZE, BUFFERS) " + q.query)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var line string
+			if err := rows.Scan(&line); err != nil {
+				rows.Close()
+				return err
+			}
+			fmt.Fprintf(out, "\t%s\n", line)
+		}
+		rows.Close()
+	}
+
+	return nil
+}
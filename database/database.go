@@ -5,17 +5,42 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"math"
+	"strings"
 
 	"github.com/TerraFactory/tilegenerator/database/entities"
+	"github.com/TerraFactory/tilegenerator/settings"
 	"github.com/TerraFactory/tilegenerator/tiles"
 	_ "github.com/lib/pq" //we want to use blank import here
 )
 
+// Names of the layers which can be configured in the "database.layers" section
+const (
+	ObjectsLayer = "objects"
+	SpecialLayer = "special"
+	TracksLayer  = "tracks"
+)
+
+// Count of segments of the shorter edge of tile envelopes transformed into the table SRID
+const envelopeSegments = 16
+
+// Snapping grid size in pixels. Coordinates are snapped to a quarter of a pixel, it's invisible on a tile.
+const snapGridPixels = 0.25
+
 // GeometryDB is a structure which represents a DB connection
 type GeometryDB struct {
-	conn      *sql.DB
-	geomtable string
-	geomcol   string
+	Name          string
+	enabledLayers []string
+	conn          *sql.DB
+	connstring    string
+	geomtable     string
+	geomcol       string
+	layers        map[string]settings.LayerSettings
+	sourceSRID    int
+	validFromCol  string
+	validToCol    string
+	propertyCols  []string
+	propertiesCol string
 }
 
 // Transfer raw sql rows into a slice of BaseGeometry structs
@@ -30,8 +55,9 @@ func (gdb *GeometryDB) rowsToMapObjects(rows *sql.Rows) ([]entities.MapObject, e
 		var wkt, label, textPosition, colorOuter, colorInner, code string
 		var isShortwaveAntenna, needShowAzimuthalGrid, needShowDirectionalDiagram bool
 		var sidelobes, beamWidth, azimut, distance, scale float64
+		var properties string
 
-		err := tmpRows.Scan(&ID, &typeID, &wkt, &label, &isShortwaveAntenna, &needShowAzimuthalGrid, &beamWidth, &sidelobes, &azimut, &distance, &needShowDirectionalDiagram, &textPosition, &colorOuter, &colorInner, &code, &scale)
+		err := tmpRows.Scan(&ID, &typeID, &wkt, &label, &isShortwaveAntenna, &needShowAzimuthalGrid, &beamWidth, &sidelobes, &azimut, &distance, &needShowDirectionalDiagram, &textPosition, &colorOuter, &colorInner, &code, &scale, &properties)
 		counter++
 
 		if err == nil {
@@ -39,6 +65,9 @@ func (gdb *GeometryDB) rowsToMapObjects(rows *sql.Rows) ([]entities.MapObject, e
 			if mapObjErr == nil {
 				mapObj.Label = label
 				mapObj.Position = textPosition
+				if propsErr := mapObj.Properties.UnmarshalJSON([]byte(properties)); propsErr != nil {
+					log.Printf("Can't read properties of object %v: %v \n", ID, propsErr)
+				}
 				mapObjects = append(mapObjects, *mapObj)
 			} else {
 				fmt.Println(errors.New("Can't create map object"))
@@ -60,8 +89,10 @@ func (gdb *GeometryDB) InitConnection(username string, connstring string, geomta
 	db, err := sql.Open(username, connstring)
 	if err == nil {
 		gdb.conn = db
+		gdb.connstring = connstring
 		gdb.geomtable = geomtable
 		gdb.geomcol = geomcol
+		gdb.sourceSRID = 4326
 	} else {
 		fmt.Printf("Database connection error: %v\n", err)
 		fmt.Printf("Database connection error: %v\n", err)
@@ -69,57 +100,167 @@ func (gdb *GeometryDB) InitConnection(username string, connstring string, geomta
 	}
 }
 
-// Return slice of all geometries in a database
-func (gdb *GeometryDB) GetGeometriesForTile(tile *tiles.Tile, situationsIds string) (mapObjects []entities.MapObject, err error) {
-	var situationQuery string
-	if situationsIds != "" {
-		situationQuery = fmt.Sprintf("situation_id in (%v) and", situationsIds)
+// NewGeometryDB creates db connection configured according to the source settings
+func NewGeometryDB(source settings.SourceSettings) *GeometryDB {
+	gdb := GeometryDB{Name: source.Name, enabledLayers: source.Layers}
+	gdb.InitConnection(source.DBInstanceName, source.DBConnectionString, source.DBGeometryTable, source.DBGeometryColumn)
+	gdb.SetSourceSRID(source.DBSourceSRID)
+	gdb.SetValidityColumns(source.DBValidFromColumn, source.DBValidToColumn)
+	gdb.SetPropertyColumns(source.DBPropertyColumns, source.DBPropertiesColumn)
+	for name, layer := range source.DBLayers {
+		gdb.SetLayer(name, layer)
 	}
-	q := fmt.Sprintf(`
-		SELECT id,type_id, ST_AsText( ST_Transform( %s, 4326 ) ), coalesce(text1, ''), coalesce(is_shortwave_antenna, false),
-		coalesce(need_show_azimuthal_grid, false), coalesce(beam_width, '1'), coalesce(sidelobes, '1'), coalesce(azimut, '0'),
-		coalesce(distance, '0'), coalesce(need_show_directional_diagram, 'false'), coalesce(text_position, 'bottom'),
-		coalesce(color_outer, ''), coalesce(color_inner, ''), coalesce(code, ''), scale  from %s
-		WHERE type_id NOT in (170, 11) and 
-		(min_zoom <= %v or min_zoom is null) and
-		(max_zoom >= %v or max_zoom is null) and %v
-		ST_Intersects(ST_SetSRID(ST_MakeBox2D(ST_Point(%v, %v), ST_Point(%v, %v)), 4326), the_geom);
-		`, gdb.geomcol, gdb.geomtable, tile.Z, tile.Z, situationQuery, tile.BoundingBox.West, tile.BoundingBox.North, tile.BoundingBox.East, tile.BoundingBox.South)
+	return &gdb
+}
 
-	rows, err := gdb.conn.Query(q)
-	if err == nil {
-		mapObjects, scanErr := gdb.rowsToMapObjects(rows)
-		return mapObjects, scanErr
-	} else {
-		fmt.Printf("Query error marker object: %v", err)
-		log.Printf("Query error marker object: %v \n", err)
-		return nil, err
+// SetPropertyColumns sets columns which are selected into MapObject.Properties. "columns" are added by their names,
+// "jsonColumn" is a JSON or JSONB column whose keys are added as well. Both are optional.
+func (gdb *GeometryDB) SetPropertyColumns(columns []string, jsonColumn string) {
+	gdb.propertyCols = columns
+	gdb.propertiesCol = jsonColumn
+}
+
+// propertiesExpression returns SQL expression which selects properties of an object as a JSON object
+func (gdb *GeometryDB) propertiesExpression() string {
+	expr := "'{}'::jsonb"
+	if gdb.propertiesCol != "" {
+		expr = fmt.Sprintf("coalesce(%s::jsonb, '{}'::jsonb)", gdb.propertiesCol)
+	}
+	if len(gdb.propertyCols) > 0 {
+		pairs := []string{}
+		for _, col := range gdb.propertyCols {
+			pairs = append(pairs, fmt.Sprintf("'%s', %s", col, col))
+		}
+		expr = fmt.Sprintf("%s || jsonb_build_object(%s)", expr, strings.Join(pairs, ", "))
 	}
+	return fmt.Sprintf("(%s)::text", expr)
+}
+
+// SetSourceSRID sets SRID of the geometry column. Tile envelopes are transformed into this SRID.
+func (gdb *GeometryDB) SetSourceSRID(srid int) {
+	gdb.sourceSRID = srid
 }
 
-func (gdb *GeometryDB) GetAllSpecialObject(tile *tiles.Tile, situationsIds string) (mapObjects []entities.MapObject, err error) {
-	var situationQuery string
-	if situationsIds != "" {
-		situationQuery = fmt.Sprintf("situation_id in (%v) and", situationsIds)
+// SetLayer sets clipping and simplification options of a layer. Tactical graphics of the special layer
+// are drawn from whole lines, so the layer is never clipped.
+func (gdb *GeometryDB) SetLayer(name string, layer settings.LayerSettings) {
+	if name == SpecialLayer && layer.Clip {
+		log.Printf("Source %s: clipping of layer %s is ignored \n", gdb.Name, name)
+		layer.Clip = false
+	}
+	if gdb.layers == nil {
+		gdb.layers = map[string]settings.LayerSettings{}
+	}
+	gdb.layers[name] = layer
+}
+
+// geometryExpression returns SQL expression which selects a geometry of the layer as WKT in EPSG:4326.
+// Geometry is clipped by the tile bounding box and simplified according to the tile zoom if the layer needs it.
+func (gdb *GeometryDB) geometryExpression(tile *tiles.Tile, layerName string) string {
+	expr := fmt.Sprintf("ST_Transform( %s, 4326 )", gdb.geomcol)
+	layer := gdb.layers[layerName]
+
+	if layer.Clip {
+		bbox := tile.BoundingBox
+		expr = fmt.Sprintf("ST_ClipByBox2D( %s, ST_MakeEnvelope(%v, %v, %v, %v, 4326) )", expr, bbox.West, bbox.South, bbox.East, bbox.North)
 	}
 
-	q := fmt.Sprintf(`SELECT id,type_id, ST_AsText( ST_Transform( %s, 4326 ) ), coalesce(text1, ''), coalesce(is_shortwave_antenna, false),
+	if layer.SimplifyTolerance > 0 {
+		pixelSize := tile.MinPixelSize()
+		expr = fmt.Sprintf("ST_SimplifyPreserveTopology( ST_SnapToGrid( %s, %v ), %v )", expr, pixelSize*snapGridPixels, pixelSize*layer.SimplifyTolerance)
+	}
+
+	return fmt.Sprintf("ST_AsText( %s )", expr)
+}
+
+// spatialFilter returns index-friendly SQL condition which selects geometries intersecting with the tile.
+// Tile envelope is transformed into the table SRID once, so an index on the geometry column can be used.
+func (gdb *GeometryDB) spatialFilter(tile *tiles.Tile) string {
+	return gdb.spatialCondition(gdb.geomcol, tile)
+}
+
+// spatialCondition returns SQL condition which selects geometries of the column intersecting the tile
+func (gdb *GeometryDB) spatialCondition(column string, tile *tiles.Tile) string {
+	envelope := gdb.tileEnvelope(tile)
+	return fmt.Sprintf("%s && %s and ST_Intersects(%s, %s)", column, envelope, column, envelope)
+}
+
+// tileEnvelope returns SQL expression of the tile bounding box in the table SRID
+func (gdb *GeometryDB) tileEnvelope(tile *tiles.Tile) string {
+	bbox := tile.BoundingBox
+	north := math.Min(bbox.North, tiles.MaxLatitude)
+	south := math.Max(bbox.South, -tiles.MaxLatitude)
+	west := math.Max(bbox.West, -180)
+	east := math.Min(bbox.East, 180)
+
+	envelope := fmt.Sprintf("ST_MakeEnvelope(%v, %v, %v, %v, 4326)", west, south, east, north)
+	if gdb.sourceSRID != 4326 {
+		/* Edges are densified, so they stay curved like in the tile when the SRID is not a Mercator one */
+		segment := math.Min(east-west, north-south) / envelopeSegments
+		envelope = fmt.Sprintf("ST_Transform( ST_Segmentize( %s, %v ), %v )", envelope, segment, gdb.sourceSRID)
+	}
+	return envelope
+}
+
+func situationFilter(situationsIds string) string {
+	if situationsIds == "" {
+		return ""
+	}
+	return fmt.Sprintf("situation_id in (%v) and", situationsIds)
+}
+
+func (gdb *GeometryDB) geometriesQuery(tile *tiles.Tile, situationsIds string, tf *TimeFilter) string {
+	distinct, order := gdb.distinctVersion(tf)
+	return fmt.Sprintf(`
+		SELECT %s id,type_id, %s, coalesce(text1, ''), coalesce(is_shortwave_antenna, false),
+		coalesce(need_show_azimuthal_grid, false), coalesce(beam_width, '1'), coalesce(sidelobes, '1'), coalesce(azimut, '0'),
+		coalesce(distance, '0'), coalesce(need_show_directional_diagram, 'false'), coalesce(text_position, 'bottom'),
+		coalesce(color_outer, ''), coalesce(color_inner, ''), coalesce(code, ''), scale, %s  from %s
+		WHERE type_id NOT in (170, 11) and 
+		(min_zoom <= %v or min_zoom is null) and
+		(max_zoom >= %v or max_zoom is null) and %v %v
+		%s %s;
+		`, distinct, gdb.geometryExpression(tile, ObjectsLayer), gdb.propertiesExpression(), gdb.geomtable, tile.Z, tile.Z, situationFilter(situationsIds), gdb.temporalFilter(tf), gdb.spatialFilter(tile), order)
+}
+
+func (gdb *GeometryDB) specialObjectsQuery(tile *tiles.Tile, situationsIds string, tf *TimeFilter) string {
+	distinct, order := gdb.distinctVersion(tf)
+	return fmt.Sprintf(`SELECT %s id,type_id, %s, coalesce(text1, ''), coalesce(is_shortwave_antenna, false),
 		coalesce(need_show_azimuthal_grid, false), coalesce(beam_width, '0'), coalesce(sidelobes, '1'),	coalesce(azimut, '1'),
 		coalesce(distance, '0'), coalesce(need_show_directional_diagram, 'false'), coalesce(text_position, 'bottom'),
-		coalesce(color_outer, ''), coalesce(color_inner, ''), coalesce(code, ''), scale  from %s 
-		WHERE (type_id BETWEEN 149 AND 165) OR (type_id IN (47,74,408,407,366,432)) and
+		coalesce(color_outer, ''), coalesce(color_inner, ''), coalesce(code, ''), scale, %s  from %s 
+		WHERE ((type_id BETWEEN 149 AND 165) OR (type_id IN (47,74,408,407,366,432))) and
 		(min_zoom <= %v or min_zoom is null) and
-		(max_zoom >= %v or max_zoom is null) and %v
-		ST_Intersects(ST_SetSRID(ST_MakeBox2D(ST_Point(%v, %v), ST_Point(%v, %v)), 4326), the_geom);`, gdb.geomcol, gdb.geomtable, tile.Z, tile.Z, situationQuery, tile.BoundingBox.West, tile.BoundingBox.North, tile.BoundingBox.East, tile.BoundingBox.South)
+		(max_zoom >= %v or max_zoom is null) and %v %v
+		%s %s;`, distinct, gdb.geometryExpression(tile, SpecialLayer), gdb.propertiesExpression(), gdb.geomtable, tile.Z, tile.Z, situationFilter(situationsIds), gdb.temporalFilter(tf), gdb.spatialFilter(tile), order)
+}
 
-	rows, err := gdb.conn.Query(q)
+// Return slice of all geometries in a database
+// Time filter "tf" is optional, versions valid now are returned without it.
+func (gdb *GeometryDB) GetGeometriesForTile(tile *tiles.Tile, situationsIds string, tf *TimeFilter) (mapObjects []entities.MapObject, err error) {
+	mapObjects, err = gdb.query(gdb.geometriesQuery(tile, situationsIds, tf))
+	if err != nil {
+		fmt.Printf("Query error marker object: %v", err)
+		log.Printf("Query error marker object: %v \n", err)
+	}
+	return mapObjects, err
+}
 
+// query runs the query and transforms its rows into a slice of map objects
+func (gdb *GeometryDB) query(q string) ([]entities.MapObject, error) {
+	rows, err := gdb.conn.Query(q)
 	if err == nil {
 		mapObjects, scanErr := gdb.rowsToMapObjects(rows)
 		return mapObjects, scanErr
-	} else {
+	}
+	return nil, err
+}
+
+func (gdb *GeometryDB) GetAllSpecialObject(tile *tiles.Tile, situationsIds string, tf *TimeFilter) (mapObjects []entities.MapObject, err error) {
+	mapObjects, err = gdb.query(gdb.specialObjectsQuery(tile, situationsIds, tf))
+	if err != nil {
 		fmt.Printf("Query error special object: %v", err)
 		log.Printf("Query error special object: %v \n", err)
-		return nil, err
 	}
+	return mapObjects, err
 }
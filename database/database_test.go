@@ -0,0 +1,61 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/TerraFactory/tilegenerator/settings"
+	"github.com/TerraFactory/tilegenerator/tiles"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeometryDB_SpatialFilter(t *testing.T) {
+	gdb := GeometryDB{geomcol: "geom", sourceSRID: 4326}
+	tile := tiles.NewTile(0, 0, 1)
+	envelope := "ST_MakeEnvelope(-180, 0, 0, 85.05112877980659, 4326)"
+	assert.Equal(t, "geom && "+envelope+" and ST_Intersects(geom, "+envelope+")", gdb.spatialFilter(tile))
+
+	/* The envelope is transformed into the table SRID, so the geometry column stays indexable */
+	gdb.SetSourceSRID(3857)
+	envelope = "ST_Transform( ST_Segmentize( " + envelope + ", 5.315695548737912 ), 3857 )"
+	assert.Equal(t, "geom && "+envelope+" and ST_Intersects(geom, "+envelope+")", gdb.spatialFilter(tile))
+}
+
+func TestGeometryDB_GeometriesQuery(t *testing.T) {
+	gdb := GeometryDB{geomtable: "maps.objects", geomcol: "geom", sourceSRID: 4326}
+	gdb.SetLayer(ObjectsLayer, settings.LayerSettings{Clip: true})
+	tile := tiles.NewTile(0, 0, 1)
+	q := gdb.geometriesQuery(tile, "3,4", nil)
+
+	assert.Contains(t, q, "ST_AsText( ST_ClipByBox2D( ST_Transform( geom, 4326 ), ST_MakeEnvelope(")
+	assert.Contains(t, q, "from maps.objects")
+	assert.Contains(t, q, "(min_zoom <= 1 or min_zoom is null) and\n\t\t(max_zoom >= 1 or max_zoom is null) and situation_id in (3,4) and")
+	assert.Contains(t, q, gdb.spatialFilter(tile))
+	assert.NotContains(t, q, "DISTINCT ON")
+
+	gdb.SetValidityColumns("valid_from", "valid_to")
+	from := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	tf := &TimeFilter{From: from, To: from.Add(time.Hour)}
+	q = gdb.geometriesQuery(tile, "", tf)
+	assert.Contains(t, q, "SELECT DISTINCT ON (id) id,type_id")
+	assert.Contains(t, q, gdb.temporalFilter(tf))
+	assert.Contains(t, q, "ORDER BY id, valid_from DESC NULLS LAST;")
+	assert.NotContains(t, q, "situation_id in")
+}
+
+func TestGeometryDB_SpecialObjectsQuery(t *testing.T) {
+	gdb := GeometryDB{geomtable: "maps.objects", geomcol: "geom", sourceSRID: 4326}
+	gdb.SetLayer(SpecialLayer, settings.LayerSettings{Clip: true, SimplifyTolerance: 1})
+	tile := tiles.NewTile(0, 0, 1)
+	q := gdb.specialObjectsQuery(tile, "3", nil)
+
+	/* The special layer is never clipped, tolerance is a pixel in degrees of latitude */
+	assert.Contains(t, q, fmt.Sprintf("ST_AsText( ST_SimplifyPreserveTopology( ST_SnapToGrid( ST_Transform( geom, 4326 ), %v ), %v ) )",
+		tile.MinPixelSize()*snapGridPixels, tile.MinPixelSize()))
+	assert.NotContains(t, q, "ST_ClipByBox2D")
+	/* Other conditions apply to objects of both type ranges */
+	assert.Contains(t, q, "WHERE ((type_id BETWEEN 149 AND 165) OR (type_id IN (47,74,408,407,366,432))) and")
+	assert.Contains(t, q, "situation_id in (3) and")
+	assert.Contains(t, q, gdb.spatialFilter(tile))
+}
@@ -27,6 +27,8 @@ type MapObject struct {
 	ColorInner                 string
 	Code                       string
 	Scale                      float64
+	IsTrack                    bool
+	Properties                 Properties
 }
 
 // NewObject creates new MapObject with a parsed from WKT geometry
@@ -51,5 +53,21 @@ func NewObject(id int, typeId int, wkt string, isAntenna, needShowAzimuthalGrid,
 		ColorOuter:                 colorOuter,
 		ColorInner:                 colorInner,
 		Code:                       code,
-		Scale:                      scale}, nil
+		Scale:                      scale,
+		Properties:                 Properties{}}, nil
+}
+
+// NewTrack creates MapObject which represents a track of the object with "id", i.e. a line through its former positions
+func NewTrack(id int, wkt string, color string) (*MapObject, error) {
+	geo, err := wktparser.Parse(wkt)
+	if err != nil {
+		fmt.Println(err.Error())
+		return nil, err
+	}
+
+	return &MapObject{
+		ID:         id,
+		Geometry:   geo,
+		ColorOuter: color,
+		IsTrack:    true}, nil
 }
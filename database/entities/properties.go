@@ -0,0 +1,63 @@
+package entities
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// Properties contains arbitrary attributes of a map object. Values are strings, float64, bool, nil
+// or nested []interface{} and map[string]interface{} as they are decoded from JSON.
+type Properties map[string]interface{}
+
+// UnmarshalJSON reads properties from a JSON object
+func (props *Properties) UnmarshalJSON(data []byte) error {
+	values := map[string]interface{}{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	*props = values
+	return nil
+}
+
+// Has returns true if the property is present and is not null
+func (props Properties) Has(name string) bool {
+	value, ok := props[name]
+	return ok && value != nil
+}
+
+// String returns the property formatted as a string and false if it is missing
+func (props Properties) String(name string) (string, bool) {
+	value, ok := props[name]
+	if !ok || value == nil {
+		return "", false
+	}
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		bytes, err := json.Marshal(v)
+		return string(bytes), err == nil
+	}
+}
+
+// Float returns a numeric property. Strings containing numbers are converted as well.
+func (props Properties) Float(name string) (float64, bool) {
+	switch v := props[name].(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// Bool returns a boolean property
+func (props Properties) Bool(name string) (bool, bool) {
+	v, ok := props[name].(bool)
+	return v, ok
+}
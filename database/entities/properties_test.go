@@ -0,0 +1,28 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProperties_UnmarshalJSON(t *testing.T) {
+	props := Properties{}
+	err := props.UnmarshalJSON([]byte(`{"callsign": "Eagle", "speed": 250.5, "friendly": true, "unit": null}`))
+	assert.Nil(t, err)
+
+	callsign, ok := props.String("callsign")
+	assert.True(t, ok)
+	assert.Equal(t, "Eagle", callsign)
+
+	speed, ok := props.Float("speed")
+	assert.True(t, ok)
+	assert.Equal(t, 250.5, speed)
+
+	friendly, ok := props.Bool("friendly")
+	assert.True(t, ok)
+	assert.True(t, friendly)
+
+	assert.False(t, props.Has("unit"))
+	assert.False(t, props.Has("missing"))
+}
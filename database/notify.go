@@ -0,0 +1,86 @@
+package database
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/TerraFactory/tilegenerator/tiles"
+	"github.com/lib/pq"
+)
+
+// Invalidation describes changed objects of the geometry table. It is received as a JSON payload of NOTIFY, e.g.
+//
+//	{"ids": [1, 2], "situation_ids": [10], "bbox": {"north": 56, "south": 55, "west": 37, "east": 38}}
+//
+// "bbox" is the extent of new geometries and "old_bbox" is the extent of geometries before the change,
+// triggers send both of them for moved objects and "old_bbox" for deleted ones.
+// Invalidation without bounding boxes means that everything should be invalidated.
+type Invalidation struct {
+	IDs            []int              `json:"ids"`
+	SituationIDs   []int              `json:"situation_ids"`
+	BoundingBox    *tiles.BoundingBox `json:"bbox"`
+	OldBoundingBox *tiles.BoundingBox `json:"old_bbox"`
+}
+
+// IsFull returns true if the whole map should be invalidated
+func (inv *Invalidation) IsFull() bool {
+	return len(inv.BoundingBoxes()) == 0
+}
+
+// BoundingBoxes returns the old and the new bounding boxes of changed objects which are set
+func (inv *Invalidation) BoundingBoxes() []tiles.BoundingBox {
+	boxes := []tiles.BoundingBox{}
+	for _, bbox := range []*tiles.BoundingBox{inv.OldBoundingBox, inv.BoundingBox} {
+		if bbox != nil {
+			boxes = append(boxes, *bbox)
+		}
+	}
+	return boxes
+}
+
+// Listen subscribes to the NOTIFY channel and calls "handler" for each notification.
+// Handler receives a full invalidation when the connection is restored, because notifications may be lost meanwhile.
+func (gdb *GeometryDB) Listen(channel string, handler func(*Invalidation)) error {
+	listener := pq.NewListener(gdb.connstring, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Notifications listener error: %v \n", err)
+		}
+	})
+	if err := listener.Listen(channel); err != nil {
+		listener.Close()
+		return err
+	}
+
+	go func() {
+		for {
+			select {
+			case notification := <-listener.Notify:
+				if notification == nil {
+					// connection was lost and restored
+					handler(&Invalidation{})
+					continue
+				}
+				inv, err := parseInvalidation(notification.Extra)
+				if err != nil {
+					// changed objects are unknown
+					log.Printf("Can't parse notification '%s', invalidating everything: %v \n", notification.Extra, err)
+					inv = &Invalidation{}
+				}
+				handler(inv)
+			case <-time.After(90 * time.Second):
+				go listener.Ping()
+			}
+		}
+	}()
+
+	return nil
+}
+
+func parseInvalidation(payload string) (*Invalidation, error) {
+	inv := Invalidation{}
+	if err := json.Unmarshal([]byte(payload), &inv); err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
@@ -0,0 +1,26 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/TerraFactory/tilegenerator/tiles"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseInvalidation(t *testing.T) {
+	inv, err := parseInvalidation(`{"ids": [1], "bbox": {"north": 56, "south": 55, "west": 37, "east": 38},
+		"old_bbox": {"north": 50, "south": 49, "west": 30, "east": 31}}`)
+	assert.Nil(t, err)
+	assert.False(t, inv.IsFull())
+	assert.Equal(t, []tiles.BoundingBox{
+		{North: 50, South: 49, West: 30, East: 31},
+		{North: 56, South: 55, West: 37, East: 38},
+	}, inv.BoundingBoxes())
+
+	inv, err = parseInvalidation(`{"ids": [1, 2]}`)
+	assert.Nil(t, err)
+	assert.True(t, inv.IsFull(), "objects without bounding boxes may be deleted or moved anywhere")
+
+	_, err = parseInvalidation(`{"ids": "1"}`)
+	assert.NotNil(t, err)
+}
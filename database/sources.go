@@ -0,0 +1,125 @@
+package database
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/TerraFactory/tilegenerator/database/entities"
+	"github.com/TerraFactory/tilegenerator/settings"
+	"github.com/TerraFactory/tilegenerator/tiles"
+)
+
+// Sources is a set of geometry databases which are queried together
+type Sources []*GeometryDB
+
+// NewSources creates connections to all sources from the settings
+func NewSources(conf *settings.Settings) Sources {
+	sources := Sources{}
+	for _, source := range conf.Sources {
+		sources = append(sources, NewGeometryDB(source))
+	}
+	return sources
+}
+
+// Listen subscribes to the NOTIFY channel once for each database, so sources sharing a connection string
+// don't handle the same notification twice. Invalidations don't depend on the source, they carry bounding boxes.
+// It returns errors of databases which can't be listened to.
+func (sources Sources) Listen(channel string, handler func(*Invalidation)) []error {
+	errs := []error{}
+	listened := map[string]bool{}
+	for _, gdb := range sources {
+		if listened[gdb.connstring] {
+			continue
+		}
+		listened[gdb.connstring] = true
+		if err := gdb.Listen(channel, handler); err != nil {
+			errs = append(errs, fmt.Errorf("Source %s: %v", gdb.Name, err))
+		}
+	}
+	return errs
+}
+
+// HasLayer returns true if the layer should be queried from this source. All layers are enabled by default.
+func (gdb *GeometryDB) HasLayer(layer string) bool {
+	if len(gdb.enabledLayers) == 0 {
+		return true
+	}
+	for _, l := range gdb.enabledLayers {
+		if l == layer {
+			return true
+		}
+	}
+	return false
+}
+
+// GetLayer returns objects of the layer for the tile
+func (gdb *GeometryDB) GetLayer(layer string, tile *tiles.Tile, situationsIds string, tf *TimeFilter) ([]entities.MapObject, error) {
+	switch layer {
+	case ObjectsLayer:
+		return gdb.GetGeometriesForTile(tile, situationsIds, tf)
+	case SpecialLayer:
+		return gdb.GetAllSpecialObject(tile, situationsIds, tf)
+	case TracksLayer:
+		return gdb.GetTracks(tile, situationsIds, tf)
+	default:
+		return nil, fmt.Errorf("Unknown layer %s", layer)
+	}
+}
+
+// SupportsTime returns true if all sources support temporal filters
+func (sources Sources) SupportsTime() bool {
+	for _, gdb := range sources {
+		if !gdb.SupportsTime() {
+			return false
+		}
+	}
+	return true
+}
+
+// GetLayers queries layers of all sources concurrently. Objects of each layer are merged in the order of sources.
+// Result contains objects of sources which were queried successfully and the first error, if any.
+func (sources Sources) GetLayers(layers []string, tile *tiles.Tile, situationsIds string, tf *TimeFilter) (map[string][]entities.MapObject, error) {
+	results := make([][]layerResult, len(layers))
+	var wg sync.WaitGroup
+	for i, layer := range layers {
+		results[i] = make([]layerResult, len(sources))
+		for j, gdb := range sources {
+			if !gdb.HasLayer(layer) {
+				continue
+			}
+			wg.Add(1)
+			go func(i, j int, layer string, gdb *GeometryDB) {
+				defer wg.Done()
+				objects, err := gdb.GetLayer(layer, tile, situationsIds, tf)
+				if err != nil {
+					err = fmt.Errorf("Source %s, layer %s: %v", gdb.Name, layer, err)
+				}
+				results[i][j] = layerResult{objects, err}
+			}(i, j, layer, gdb)
+		}
+	}
+	wg.Wait()
+	return mergeLayers(layers, results)
+}
+
+// layerResult is objects of a layer queried from one source
+type layerResult struct {
+	objects []entities.MapObject
+	err     error
+}
+
+// mergeLayers concatenates results of each layer in the order of sources and returns the first error in this order
+func mergeLayers(layers []string, results [][]layerResult) (map[string][]entities.MapObject, error) {
+	var firstErr error
+	merged := map[string][]entities.MapObject{}
+	for i, layer := range layers {
+		merged[layer] = []entities.MapObject{}
+		for _, r := range results[i] {
+			if r.err != nil && firstErr == nil {
+				firstErr = r.err
+			}
+			merged[layer] = append(merged[layer], r.objects...)
+		}
+	}
+	return merged, firstErr
+}
@@ -0,0 +1,32 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/TerraFactory/tilegenerator/database/entities"
+	"github.com/TerraFactory/tilegenerator/tiles"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeLayers(t *testing.T) {
+	first, second := errors.New("first"), errors.New("second")
+	merged, err := mergeLayers([]string{ObjectsLayer, SpecialLayer}, [][]layerResult{
+		{{objects: []entities.MapObject{{ID: 1}}}, {err: first}, {objects: []entities.MapObject{{ID: 2}, {ID: 3}}}},
+		{{err: second}, {}, {objects: []entities.MapObject{{ID: 4}}}},
+	})
+	assert.Equal(t, first, err, "the first error in order of layers and sources is returned")
+	assert.Equal(t, []entities.MapObject{{ID: 1}, {ID: 2}, {ID: 3}}, merged[ObjectsLayer])
+	assert.Equal(t, []entities.MapObject{{ID: 4}}, merged[SpecialLayer])
+}
+
+func TestSources_GetLayers(t *testing.T) {
+	sources := Sources{
+		{Name: "planned", enabledLayers: []string{ObjectsLayer}},
+		{Name: "actual"},
+		{Name: "archive"},
+	}
+	merged, err := sources.GetLayers([]string{"unknown"}, tiles.NewTile(0, 0, 1), "", nil)
+	assert.EqualError(t, err, "Source actual, layer unknown: Unknown layer unknown")
+	assert.Equal(t, []entities.MapObject{}, merged["unknown"])
+}
@@ -0,0 +1,112 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/TerraFactory/tilegenerator/database/entities"
+	"github.com/TerraFactory/tilegenerator/tiles"
+)
+
+// TimeFilter selects versions of objects which were valid at some moment (From equals To) or within the interval
+type TimeFilter struct {
+	From time.Time
+	To   time.Time
+}
+
+// IsInstant returns true if the filter selects the situation as of one moment
+func (tf *TimeFilter) IsInstant() bool {
+	return tf.From.Equal(tf.To)
+}
+
+// String returns a representation of the filter which can be used as a part of cache keys
+func (tf *TimeFilter) String() string {
+	if tf.IsInstant() {
+		return tf.From.UTC().Format(time.RFC3339Nano)
+	}
+	return tf.From.UTC().Format(time.RFC3339Nano) + "/" + tf.To.UTC().Format(time.RFC3339Nano)
+}
+
+func sqlTime(t time.Time) string {
+	return fmt.Sprintf("'%s'::timestamptz", t.UTC().Format(time.RFC3339Nano))
+}
+
+// SetValidityColumns sets columns which contain beginning and end of the validity interval of object versions.
+// Empty column names disable temporal filters.
+func (gdb *GeometryDB) SetValidityColumns(validFrom, validTo string) {
+	gdb.validFromCol = validFrom
+	gdb.validToCol = validTo
+}
+
+// SupportsTime returns true if validity columns are configured
+func (gdb *GeometryDB) SupportsTime() bool {
+	return gdb.validFromCol != "" && gdb.validToCol != ""
+}
+
+// temporalFilter returns SQL condition which selects versions valid at the moment or within the interval.
+// Without a time filter versions valid now are selected.
+func (gdb *GeometryDB) temporalFilter(tf *TimeFilter) string {
+	if !gdb.SupportsTime() {
+		return ""
+	}
+	from, to := "now()", "now()"
+	if tf != nil {
+		from, to = sqlTime(tf.From), sqlTime(tf.To)
+	}
+	return fmt.Sprintf("(%s <= %s or %s is null) and (%s > %s or %s is null) and",
+		gdb.validFromCol, to, gdb.validFromCol, gdb.validToCol, from, gdb.validToCol)
+}
+
+// distinctVersion returns DISTINCT ON clause and ORDER BY clause which keep only the latest version of each object
+// selected by the interval filter
+func (gdb *GeometryDB) distinctVersion(tf *TimeFilter) (string, string) {
+	if !gdb.SupportsTime() || tf == nil || tf.IsInstant() {
+		return "", ""
+	}
+	return "DISTINCT ON (id)", fmt.Sprintf("ORDER BY id, %s DESC NULLS LAST", gdb.validFromCol)
+}
+
+// GetTracks returns tracks of point objects whose position changed within the interval of the time filter.
+// Each track is a line string made of the object positions ordered by time, whole tracks crossing the tile are returned.
+func (gdb *GeometryDB) GetTracks(tile *tiles.Tile, situationsIds string, tf *TimeFilter) ([]entities.MapObject, error) {
+	if !gdb.SupportsTime() || tf == nil || tf.IsInstant() {
+		return []entities.MapObject{}, nil
+	}
+
+	rows, err := gdb.conn.Query(gdb.tracksQuery(tile, situationsIds, tf))
+	if err != nil {
+		fmt.Printf("Query error tracks: %v", err)
+		log.Printf("Query error tracks: %v \n", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	tracks := []entities.MapObject{}
+	for rows.Next() {
+		var id int
+		var wkt, color string
+		if err := rows.Scan(&id, &wkt, &color); err != nil {
+			log.Println(err)
+			continue
+		}
+		if track, err := entities.NewTrack(id, wkt, color); err == nil {
+			tracks = append(tracks, *track)
+		}
+	}
+	return tracks, nil
+}
+
+// tracksQuery makes lines of all positions of objects having a position in the tile and selects the lines
+// which intersect the tile, so tracks don't change at tile borders
+func (gdb *GeometryDB) tracksQuery(tile *tiles.Tile, situationsIds string, tf *TimeFilter) string {
+	filter := fmt.Sprintf("GeometryType(%s) = 'POINT' and %v %v", gdb.geomcol, situationFilter(situationsIds), gdb.temporalFilter(tf))
+	return fmt.Sprintf(`
+		SELECT id, ST_AsText( ST_Transform( track, 4326 ) ), color FROM
+		(SELECT id, ST_MakeLine( %s ORDER BY %s ) AS track, coalesce(max(color_outer), '') AS color from %s
+		WHERE %s id IN (SELECT id from %s WHERE %s %s && %s)
+		GROUP BY id HAVING count(DISTINCT %s) > 1) AS tracks
+		WHERE %s;`,
+		gdb.geomcol, gdb.validFromCol, gdb.geomtable, filter, gdb.geomtable, filter, gdb.geomcol, gdb.tileEnvelope(tile),
+		gdb.geomcol, gdb.spatialCondition("track", tile))
+}
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TerraFactory/tilegenerator/tiles"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeometryDB_TemporalFilter(t *testing.T) {
+	gdb := GeometryDB{}
+	assert.Equal(t, "", gdb.temporalFilter(nil))
+
+	gdb.SetValidityColumns("valid_from", "valid_to")
+	assert.Equal(t, "(valid_from <= now() or valid_from is null) and (valid_to > now() or valid_to is null) and",
+		gdb.temporalFilter(nil))
+
+	from := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	tf := &TimeFilter{From: from, To: from.Add(time.Hour)}
+	assert.Equal(t, "(valid_from <= '2020-05-01T11:00:00Z'::timestamptz or valid_from is null) and "+
+		"(valid_to > '2020-05-01T10:00:00Z'::timestamptz or valid_to is null) and", gdb.temporalFilter(tf))
+}
+
+func TestGeometryDB_TracksQuery(t *testing.T) {
+	gdb := GeometryDB{geomtable: "maps.objects", geomcol: "geom", sourceSRID: 4326}
+	gdb.SetValidityColumns("valid_from", "valid_to")
+	from := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	q := gdb.tracksQuery(tiles.NewTile(0, 0, 1), "3", &TimeFilter{From: from, To: from.Add(time.Hour)})
+
+	assert.Contains(t, q, "ST_MakeLine( geom ORDER BY valid_from ) AS track")
+	assert.Contains(t, q, "situation_id in (3) and")
+	assert.Contains(t, q, ") AS tracks\n\t\tWHERE track && ST_MakeEnvelope(",
+		"tracks are built from all positions and filtered by the tile afterwards")
+	assert.Contains(t, q, "id IN (SELECT id from maps.objects WHERE GeometryType(geom) = 'POINT' and situation_id in (3) and",
+		"only objects having a position in the tile are aggregated")
+	assert.Contains(t, q, "geom && ST_MakeEnvelope(-180, 0, 0, 85.05112877980659, 4326))\n")
+}
@@ -0,0 +1,162 @@
+package listeners
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/TerraFactory/tilegenerator/cache"
+	"github.com/TerraFactory/tilegenerator/database"
+	"github.com/TerraFactory/tilegenerator/tiles"
+)
+
+// invalidationEvent is sent to clients subscribed to tile events.
+// All == true means that all tiles should be reloaded.
+type invalidationEvent struct {
+	All          bool              `json:"all,omitempty"`
+	IDs          []int             `json:"ids,omitempty"`
+	SituationIDs []int             `json:"situation_ids,omitempty"`
+	Tiles        []tiles.TileRange `json:"tiles,omitempty"`
+}
+
+type eventsClient struct {
+	events     chan []byte
+	situations map[int]bool
+}
+
+// wants returns true if the client is interested in changes of these situations
+func (client *eventsClient) wants(situationIDs []int) bool {
+	if len(client.situations) == 0 || len(situationIDs) == 0 {
+		return true
+	}
+	for _, id := range situationIDs {
+		if client.situations[id] {
+			return true
+		}
+	}
+	return false
+}
+
+// eventsHub keeps clients subscribed to Server-Sent Events
+type eventsHub struct {
+	mutex   sync.Mutex
+	clients map[*eventsClient]bool
+}
+
+func newEventsHub() *eventsHub {
+	return &eventsHub{clients: map[*eventsClient]bool{}}
+}
+
+func (hub *eventsHub) subscribe(situations map[int]bool) *eventsClient {
+	client := &eventsClient{events: make(chan []byte, 16), situations: situations}
+	hub.mutex.Lock()
+	hub.clients[client] = true
+	hub.mutex.Unlock()
+	return client
+}
+
+func (hub *eventsHub) unsubscribe(client *eventsClient) {
+	hub.mutex.Lock()
+	delete(hub.clients, client)
+	hub.mutex.Unlock()
+}
+
+func (hub *eventsHub) publish(event *invalidationEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Can't marshal tile event: %v \n", err)
+		return
+	}
+
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+	for client := range hub.clients {
+		if !event.All && !client.wants(event.SituationIDs) {
+			continue
+		}
+		select {
+		case client.events <- data:
+		default:
+			log.Println("Tile events client is too slow, event is dropped")
+		}
+	}
+}
+
+func parseSituations(situations string) map[int]bool {
+	result := map[int]bool{}
+	for _, s := range strings.Split(situations, ",") {
+		if id, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+			result[id] = true
+		}
+	}
+	return result
+}
+
+// getEvents streams tile invalidation events to a client as Server-Sent Events
+func (srv *server) getEvents(writer http.ResponseWriter, req *http.Request) {
+	flusher, ok := writer.(http.Flusher)
+	if !ok {
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var situations string
+	if parseErr := req.ParseForm(); parseErr == nil {
+		situations = req.Form.Get("situations")
+	}
+
+	client := srv.events.subscribe(parseSituations(situations))
+	defer srv.events.unsubscribe(client)
+
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.Header().Set("Connection", "keep-alive")
+	writer.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case data := <-client.events:
+			fmt.Fprintf(writer, "event: invalidate\ndata: %s\n\n", data)
+			flusher.Flush()
+		case <-req.Context().Done():
+			return
+		}
+	}
+}
+
+// invalidate purges changed tiles from the cache and notifies subscribed clients
+func (srv *server) invalidate(inv *database.Invalidation, minZoom, maxZoom int) {
+	if inv.IsFull() {
+		if srv.tileCache != nil {
+			srv.tileCache.Purge()
+		}
+		srv.events.publish(&invalidationEvent{All: true})
+		return
+	}
+
+	boxes := inv.BoundingBoxes()
+	if srv.tileCache != nil {
+		purged := srv.tileCache.PurgeFunc(func(key cache.Key) bool {
+			for _, bbox := range boxes {
+				if tiles.NewTileRange(bbox, key.Z).Contains(key.Z, key.X, key.Y) {
+					return true
+				}
+			}
+			return false
+		})
+		log.Printf("Objects %v changed, %v cached tiles purged \n", inv.IDs, purged)
+	}
+
+	event := invalidationEvent{IDs: inv.IDs, SituationIDs: inv.SituationIDs}
+	for z := minZoom; z <= maxZoom; z++ {
+		for _, bbox := range boxes {
+			event.Tiles = append(event.Tiles, tiles.NewTileRange(bbox, z))
+		}
+	}
+	srv.events.publish(&event)
+}
@@ -1,40 +1,114 @@
 package listeners
 
 import (
+	"bytes"
+	"expvar"
 	"fmt"
 	"log"
 	"net/http"
+	"path/filepath"
+	"reflect"
 	"strconv"
+	"time"
 
+	"github.com/TerraFactory/tilegenerator/assets"
+	"github.com/TerraFactory/tilegenerator/cache"
 	"github.com/TerraFactory/tilegenerator/database"
 	"github.com/TerraFactory/tilegenerator/database/entities"
 	"github.com/TerraFactory/tilegenerator/settings"
 	"github.com/TerraFactory/tilegenerator/settings/styling"
+	"github.com/TerraFactory/tilegenerator/symbology"
 	"github.com/TerraFactory/tilegenerator/tiles"
 	"github.com/fatih/color"
 	"github.com/gorilla/mux"
 )
 
-var db database.GeometryDB
-var styles *map[string]styling.Style
+// defaultStyleName is the style of objects of the objects layer, styles without a filter match objects by it.
+// Objects of the special layer have no style name, they are drawn by styles with filters and tactical graphics.
+const defaultStyleName = "home"
+
+// server handles tile requests of one configuration
+type server struct {
+	sources   database.Sources
+	tileCache *cache.TileCache
+	renderer  *tiles.Renderer
+	events    *eventsHub
+	// graphicsFile maps object codes to tactical graphics, it is reloaded with styles
+	graphicsFile string
+}
 
 func printStartingMsg(config *settings.Settings) {
 	fmt.Printf("Starting with the following settings:\n")
-	fmt.Printf("\tGeometry table: %s\n", color.CyanString(config.DBGeometryTable))
-	fmt.Printf("\tGeometry column: %s\n", color.CyanString(config.DBGeometryColumn))
+	for _, source := range config.Sources {
+		fmt.Printf("\tSource %s: %s.%s\n", color.CyanString(source.Name), color.CyanString(source.DBGeometryTable), color.CyanString(source.DBGeometryColumn))
+	}
 	fmt.Printf("\tHTTP port: %s\n", color.CyanString(config.HTTPPort))
 	fmt.Printf("\tLogging directory: %s\n", color.CyanString(config.LogDirectory))
 	color.Green("\n Started!\n")
 	log.Println("Started!")
 }
 
-func getTile(writer http.ResponseWriter, req *http.Request) {
+// parseTimeFilter parses "time" or "from"/"to" request parameters in RFC 3339 format.
+// It returns nil if none of them are set. "to" defaults to the current time, such intervals are not cached.
+func parseTimeFilter(moment, from, to string) (*database.TimeFilter, error) {
+	if moment != "" {
+		t, err := time.Parse(time.RFC3339, moment)
+		if err != nil {
+			return nil, err
+		}
+		return &database.TimeFilter{From: t, To: t}, nil
+	}
+	if from == "" {
+		return nil, nil
+	}
+
+	fromTime, err := time.Parse(time.RFC3339, from)
+	if err != nil {
+		return nil, err
+	}
+	toTime := time.Now()
+	if to != "" {
+		if toTime, err = time.Parse(time.RFC3339, to); err != nil {
+			return nil, err
+		}
+	}
+	if toTime.Before(fromTime) {
+		return nil, fmt.Errorf("Wrong time interval %s - %s", from, to)
+	}
+	return &database.TimeFilter{From: fromTime, To: toTime}, nil
+}
+
+// cacheQuery returns request parameters which affect rendering as a part of the tile cache key
+func cacheQuery(situations string, timeFilter *database.TimeFilter, tracks bool) string {
+	query := "situations=" + situations
+	if timeFilter != nil {
+		query += "&time=" + timeFilter.String() + "&tracks=" + strconv.FormatBool(tracks)
+	}
+	return query
+}
+
+func (srv *server) getTile(writer http.ResponseWriter, req *http.Request) {
 	objects := []entities.MapObject{}
 	vars := mux.Vars(req)
 	var situations string
+	var timeFilter *database.TimeFilter
+	var tracks bool
+	cached := srv.tileCache != nil
 
 	if parseErr := req.ParseForm(); parseErr == nil {
 		situations = req.Form.Get("situations")
+		tracks = req.Form.Get("tracks") == "true"
+
+		var timeErr error
+		timeFilter, timeErr = parseTimeFilter(req.Form.Get("time"), req.Form.Get("from"), req.Form.Get("to"))
+		if timeErr != nil || (timeFilter != nil && !srv.sources.SupportsTime()) {
+			writer.WriteHeader(400)
+			return
+		}
+		if timeFilter != nil && req.Form.Get("time") == "" && req.Form.Get("to") == "" {
+			// the interval ends now and changes with every request
+			cached = false
+		}
 	}
 
 	x, errX := strconv.Atoi(vars["x"])
@@ -45,40 +119,183 @@ func getTile(writer http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	cacheKey := cache.Key{Z: z, X: x, Y: y, Query: cacheQuery(situations, timeFilter, tracks)}
+	if cached {
+		if content, ok := srv.tileCache.Get(cacheKey); ok {
+			writer.Header().Set("Content-Type", "image/svg+xml")
+			writer.Write(content)
+			return
+		}
+	}
+
+	/* Tiles purged while objects are read and rendered are not cached */
+	var generation uint64
+	if cached {
+		generation = srv.tileCache.Generation()
+	}
+
 	tile := tiles.NewTile(x, y, z)
 	tile.BoundingBox.AddMargin()
 
-	dbMapsObjects, dbErr := db.GetGeometriesForTile(tile, situations)
-	if dbErr == nil {
-		for _, obj := range dbMapsObjects {
-			obj.StyleName = "home"
-			objects = append(objects, obj)
-		}
+	layers := []string{database.ObjectsLayer, database.SpecialLayer}
+	if tracks {
+		layers = append([]string{database.TracksLayer}, layers...)
 	}
-	specialObjects, err := db.GetAllSpecialObject(tile, situations)
-	if err == nil {
-		for _, obj := range specialObjects {
-			// obj.StyleName = "home"
-			objects = append(objects, obj)
-		}
+	layerObjects, err := srv.sources.GetLayers(layers, tile, situations, timeFilter)
+	if err != nil {
+		log.Println(err)
+	}
+
+	objects = append(objects, layerObjects[database.TracksLayer]...)
+	for _, obj := range layerObjects[database.ObjectsLayer] {
+		obj.StyleName = defaultStyleName
+		objects = append(objects, obj)
+	}
+	objects = append(objects, layerObjects[database.SpecialLayer]...)
+
+	var buffer bytes.Buffer
+	usedStyles := srv.renderer.RenderTile(tile, &objects, &buffer)
+	if cached && err == nil {
+		srv.tileCache.SetTaggedSince(generation, cacheKey, buffer.Bytes(), usedStyles)
 	}
 
 	writer.Header().Set("Content-Type", "image/svg+xml")
-	tiles.RenderTile(tile, &objects, styles, writer)
+	writer.Write(buffer.Bytes())
+}
+
+// reloadGraphics reads the graphics file again and returns true if the registry of the renderer is replaced.
+// Current graphics are kept if the file has errors.
+func (srv *server) reloadGraphics() bool {
+	if srv.graphicsFile == "" {
+		return false
+	}
+	graphics, err := tiles.LoadGraphicsFile(srv.graphicsFile)
+	if err != nil {
+		fmt.Printf("Graphics are not reloaded: %v\n", err)
+		log.Printf("Graphics are not reloaded: %v \n", err)
+		return false
+	}
+	if reflect.DeepEqual(graphics, srv.renderer.Graphics()) {
+		return false
+	}
+	srv.renderer.SetGraphics(graphics)
+	log.Printf("Graphics are reloaded from %s \n", srv.graphicsFile)
+	return true
+}
+
+// reloadStyles reloads graphics, purges tiles rendered with changed styles and notifies subscribed clients.
+// Tiles being rendered with previous styles are not cached after the purge (see cache.TileCache.Generation).
+func (srv *server) reloadStyles(previous, styles *map[string]styling.Style) {
+	if srv.reloadGraphics() {
+		if srv.tileCache != nil {
+			srv.tileCache.Purge()
+			log.Printf("Graphics changed, all cached tiles purged \n")
+		}
+		srv.events.publish(&invalidationEvent{All: true})
+		return
+	}
+	names, matchingChanged := styling.ChangedStyles(previous, styles)
+	if len(names) == 0 {
+		return
+	}
+	if srv.tileCache != nil {
+		if matchingChanged {
+			srv.tileCache.Purge()
+			log.Printf("Styles %v changed, all cached tiles purged \n", names)
+		} else {
+			purged := srv.tileCache.PurgeTags(names)
+			log.Printf("Styles %v changed, %v cached tiles purged \n", names, purged)
+		}
+	}
+	srv.events.publish(&invalidationEvent{All: true})
 }
 
 func StartApplication(conf *settings.Settings) {
 	/* connect to DB */
 	/* pool of connections needed here later. */
-	db = database.GeometryDB{}
-	db.InitConnection(conf.DBInstanceName, conf.DBConnectionString, conf.DBGeometryTable, conf.DBGeometryColumn)
+	srv := &server{sources: database.NewSources(conf), events: newEventsHub()}
+
+	if conf.CacheSize > 0 {
+		srv.tileCache = cache.NewTileCache(conf.CacheSize)
+	}
+
+	/* Subscribe to changes of objects */
+	if conf.NotifyChannel != "" {
+		errs := srv.sources.Listen(conf.NotifyChannel, func(inv *database.Invalidation) {
+			srv.invalidate(inv, conf.NotifyMinZoom, conf.NotifyMaxZoom)
+		})
+		for _, err := range errs {
+			fmt.Printf("Can't listen to notifications channel %s: %v\n", conf.NotifyChannel, err)
+			log.Printf("Can't listen to notifications channel %s: %v \n", conf.NotifyChannel, err)
+		}
+	}
+
+	/* Images fetched by URL are shared by all tiles */
+	fetcher := assets.NewFetcher(assets.FetcherOptions{
+		CacheSize:      conf.ImagesCacheSize,
+		CacheDirectory: conf.ImagesCacheDir,
+		Timeout:        time.Duration(conf.ImagesTimeout) * time.Millisecond,
+		MaxPerHost:     conf.ImagesMaxPerHost,
+		TTL:            time.Duration(conf.ImagesTTL) * time.Second,
+		FailureTTL:     time.Duration(conf.ImagesFailureTTL) * time.Second,
+	})
+
+	/* Read mapping of object codes to military symbols */
+	var codes symbology.Codes
+	if conf.SymbologyCodes != "" {
+		var err error
+		if codes, err = symbology.LoadCodes(conf.SymbologyCodes); err != nil {
+			fmt.Println(err.Error())
+			log.Fatal(err)
+		}
+	}
+
+	/* Read symbols of asset stores, styles refer to them */
+	stores := assets.NewStores()
+	if err := stores.LoadAll(conf.Assets); err != nil {
+		fmt.Println(err.Error())
+		log.Fatal(err)
+	}
 
 	/* Read styles from file system */
-	styles, _ = styling.GetStyles(conf)
+	styles, stylesErrors := styling.ReadStyles(conf.StylesDirectory)
+	if len(stylesErrors) > 0 {
+		color.Red("\n %v problems found in styles directory %s:\n", len(stylesErrors), conf.StylesDirectory)
+		for _, err := range stylesErrors {
+			fmt.Printf("\t%v\n", err)
+			log.Printf("Style error: %v \n", err)
+		}
+		if conf.StylesStrict && styling.HasErrors(stylesErrors) {
+			log.Fatal("Styles have errors, refusing to start because styles.strict is set")
+		}
+	}
+	styleSet := styling.NewStyleSet(styles)
+
+	/* Read mapping of object codes to tactical graphics */
+	srv.graphicsFile = filepath.Join(conf.StylesDirectory, styling.GraphicsFile)
+	graphics, err := tiles.LoadGraphicsFile(srv.graphicsFile)
+	if err != nil {
+		fmt.Println(err.Error())
+		log.Fatal(err)
+	}
+	srv.renderer = tiles.NewRenderer(conf, tiles.RendererOptions{
+		Styles:   styleSet.Styles,
+		Graphics: graphics,
+		Fetcher:  fetcher,
+		Stores:   stores,
+		Codes:    codes,
+		Logger:   tiles.StandardLogger,
+	})
+
+	if conf.StylesWatch {
+		styling.WatchStyles(conf.StylesDirectory, time.Duration(conf.StylesWatchTimeout)*time.Millisecond, conf.StylesStrict, styleSet, srv.reloadStyles)
+	}
 
 	/* Create router and start listening */
 	router := mux.NewRouter().StrictSlash(true)
-	router.HandleFunc("/tiles/{z}/{x}/{y}.svg", getTile)
+	router.HandleFunc("/tiles/events", srv.getEvents)
+	router.Handle("/debug/vars", expvar.Handler())
+	router.HandleFunc("/tiles/{z}/{x}/{y}.svg", srv.getTile)
 	printStartingMsg(conf)
 	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%s", conf.HTTPPort), router))
 }
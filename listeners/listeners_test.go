@@ -0,0 +1,78 @@
+package listeners
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/TerraFactory/tilegenerator/cache"
+	"github.com/TerraFactory/tilegenerator/settings"
+	"github.com/TerraFactory/tilegenerator/settings/styling"
+	"github.com/TerraFactory/tilegenerator/tiles"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTimeFilter(t *testing.T) {
+	tf, err := parseTimeFilter("", "", "")
+	assert.Nil(t, err)
+	assert.Nil(t, tf)
+
+	tf, err = parseTimeFilter("2020-05-01T10:00:00Z", "", "")
+	assert.Nil(t, err)
+	assert.True(t, tf.IsInstant())
+	assert.Equal(t, "2020-05-01T10:00:00Z", tf.String())
+
+	tf, err = parseTimeFilter("", "2020-05-01T10:00:00Z", "2020-05-02T10:00:00+03:00")
+	assert.Nil(t, err)
+	assert.Equal(t, "2020-05-01T10:00:00Z/2020-05-02T07:00:00Z", tf.String())
+
+	before := time.Now()
+	tf, err = parseTimeFilter("", "2020-05-01T10:00:00Z", "")
+	assert.Nil(t, err)
+	assert.False(t, tf.To.Before(before), "open interval ends now")
+
+	_, err = parseTimeFilter("", "2020-05-02T10:00:00Z", "2020-05-01T10:00:00Z")
+	assert.NotNil(t, err)
+	_, err = parseTimeFilter("yesterday", "", "")
+	assert.NotNil(t, err)
+	_, err = parseTimeFilter("", "2020-05-01T10:00:00Z", "now")
+	assert.NotNil(t, err)
+}
+
+func TestServer_ReloadStyles(t *testing.T) {
+	srv := &server{tileCache: cache.NewTileCache(10), events: newEventsHub()}
+	previous := map[string]styling.Style{"home": {Name: "home"}}
+	styles := map[string]styling.Style{"home": {Name: "home", Priority: 1}}
+
+	/* A tile rendered with previous styles while they are reloaded is not cached */
+	generation := srv.tileCache.Generation()
+	srv.reloadStyles(&previous, &styles)
+	assert.False(t, srv.tileCache.SetTaggedSince(generation, cache.Key{Z: 1}, []byte("tile"), []string{"home"}))
+	assert.Equal(t, 0, srv.tileCache.Len())
+}
+
+func TestServer_ReloadGraphics(t *testing.T) {
+	file, err := ioutil.TempFile("", "graphics")
+	assert.Nil(t, err)
+	defer os.Remove(file.Name())
+	file.WriteString("[[graphics]]\n  Codes = [\"777\"]\n  Graphic = \"pit\"\n")
+	file.Close()
+
+	srv := &server{tileCache: cache.NewTileCache(10), events: newEventsHub(), graphicsFile: file.Name()}
+	srv.renderer = tiles.NewRenderer(&settings.Settings{}, tiles.RendererOptions{})
+	styles := map[string]styling.Style{"home": {Name: "home"}}
+
+	/* Graphics are reloaded even if styles are not changed */
+	srv.tileCache.Set(cache.Key{Z: 1}, []byte("tile"))
+	srv.reloadStyles(&styles, &styles)
+	assert.Equal(t, "pit", srv.renderer.Graphics().Match("777").Graphic)
+	assert.Equal(t, 0, srv.tileCache.Len())
+
+	/* Graphics with errors are not applied */
+	ioutil.WriteFile(file.Name(), []byte("[[graphics]]\n  Codes = [\"888\"]\n  Graphic = \"pit\"\n  Width = 3\n"), 0644)
+	srv.tileCache.Set(cache.Key{Z: 1}, []byte("tile"))
+	srv.reloadStyles(&styles, &styles)
+	assert.NotNil(t, srv.renderer.Graphics().Match("777"))
+	assert.Equal(t, 1, srv.tileCache.Len())
+}
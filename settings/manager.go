@@ -9,16 +9,64 @@ import (
 	"github.com/pelletier/go-toml"
 )
 
+// LayerSettings contains per-layer options of the geometry queries.
+// Clip enables clipping of geometries by the tile bounding box,
+// SimplifyTolerance is a simplification tolerance in pixels at the requested zoom (0 disables simplification).
+type LayerSettings struct {
+	Clip              bool
+	SimplifyTolerance float64
+}
+
+// SourceSettings describes one geometry table. Tiles are rendered from objects of all sources.
+// Layers lists layers (see database.ObjectsLayer etc.) which are queried from this source.
+type SourceSettings struct {
+	Name               string
+	DBConnectionString string
+	DBGeometryTable    string
+	DBGeometryColumn   string
+	DBInstanceName     string
+	DBSourceSRID       int
+	DBValidFromColumn  string
+	DBValidToColumn    string
+	DBPropertyColumns  []string
+	DBPropertiesColumn string
+	DBLayers           map[string]LayerSettings
+	Layers             []string
+}
+
 // Settings is a singleton object, which contains configuration of a tile server
 type Settings struct {
 	DBConnectionString string
 	DBGeometryTable    string
 	DBGeometryColumn   string
 	DBInstanceName     string
+	DBSourceSRID       int
+	DBValidFromColumn  string
+	DBValidToColumn    string
+	DBPropertyColumns  []string
+	DBPropertiesColumn string
 	HTTPPort           string
 	StylesDirectory    string
+	StylesStrict       bool
+	StylesWatch        bool
+	StylesWatchTimeout int
+	Assets             map[string]string
+	SymbologyCodes     string
+	ImagesCacheSize    int
+	ImagesCacheDir     string
+	ImagesTimeout      int
+	ImagesMaxPerHost   int
+	ImagesTTL          int
+	ImagesFailureTTL   int
 	UrlAPI             string
 	LogDirectory       string
+	Debug              bool
+	DBLayers           map[string]LayerSettings
+	NotifyChannel      string
+	NotifyMinZoom      int
+	NotifyMaxZoom      int
+	CacheSize          int
+	Sources            []SourceSettings
 }
 
 var instance *Settings
@@ -34,19 +82,183 @@ func readSettings(conf_path *string) (*Settings, error) {
 		return nil, err
 	} else {
 		settings = Settings{
-			DBConnectionString: config.Get("database.connection_string").(string),
-			DBGeometryTable:    config.Get("database.geometry_table").(string),
-			DBGeometryColumn:   config.Get("database.geometry_column").(string),
-			DBInstanceName:     config.Get("database.instance_name").(string),
+			DBConnectionString: getString(config, "database.connection_string", ""),
+			DBGeometryTable:    getString(config, "database.geometry_table", ""),
+			DBGeometryColumn:   getString(config, "database.geometry_column", ""),
+			DBInstanceName:     getString(config, "database.instance_name", "postgres"),
+			DBSourceSRID:       getInt(config, "database.source_srid", 4326),
+			DBValidFromColumn:  getString(config, "database.valid_from_column", ""),
+			DBValidToColumn:    getString(config, "database.valid_to_column", ""),
+			DBPropertyColumns:  getStrings(config, "database.property_columns"),
+			DBPropertiesColumn: getString(config, "database.properties_column", ""),
 			HTTPPort:           config.Get("http.port").(string),
 			StylesDirectory:    config.Get("styles.directory").(string),
+			StylesStrict:       getBool(config, "styles.strict", false),
+			StylesWatch:        getBool(config, "styles.watch", false),
+			StylesWatchTimeout: getInt(config, "styles.watch_timeout", 1000),
 			UrlAPI:             config.Get("api.url").(string),
 			LogDirectory:       config.Get("logging.directory").(string),
+			Debug:              getBool(config, "logging.debug", false),
+			DBLayers:           readLayers(config.Get("database.layers"), nil),
+			NotifyChannel:      getString(config, "notifications.channel", ""),
+			NotifyMinZoom:      getInt(config, "notifications.min_zoom", 0),
+			NotifyMaxZoom:      getInt(config, "notifications.max_zoom", 18),
+			CacheSize:          getInt(config, "cache.size", 0),
+			Assets:             readAssets(config.Get("assets")),
+			SymbologyCodes:     getString(config, "symbology.codes", ""),
+			ImagesCacheSize:    getInt(config, "images.cache_size", 1000),
+			ImagesCacheDir:     getString(config, "images.cache_directory", ""),
+			ImagesTimeout:      getInt(config, "images.timeout", 5000),
+			ImagesMaxPerHost:   getInt(config, "images.max_per_host", 4),
+			ImagesTTL:          getInt(config, "images.ttl", 3600),
+			ImagesFailureTTL:   getInt(config, "images.failure_ttl", 30),
+		}
+		if settings.Sources, err = readSources(config, &settings); err != nil {
+			return nil, err
 		}
 	}
 	return &settings, nil
 }
 
+// readSources reads [[sources]] array. If it is missing, the [database] section is the only source.
+func readSources(config *toml.TomlTree, settings *Settings) ([]SourceSettings, error) {
+	trees, ok := config.Get("sources").([]*toml.TomlTree)
+	if !ok || len(trees) == 0 {
+		if settings.DBConnectionString == "" {
+			return nil, errors.New("Error. Neither [database] section nor [[sources]] are configured.")
+		}
+		return []SourceSettings{{
+			Name:               "default",
+			DBConnectionString: settings.DBConnectionString,
+			DBGeometryTable:    settings.DBGeometryTable,
+			DBGeometryColumn:   settings.DBGeometryColumn,
+			DBInstanceName:     settings.DBInstanceName,
+			DBSourceSRID:       settings.DBSourceSRID,
+			DBValidFromColumn:  settings.DBValidFromColumn,
+			DBValidToColumn:    settings.DBValidToColumn,
+			DBPropertyColumns:  settings.DBPropertyColumns,
+			DBPropertiesColumn: settings.DBPropertiesColumn,
+			DBLayers:           settings.DBLayers,
+		}}, nil
+	}
+
+	sources := []SourceSettings{}
+	names := map[string]bool{}
+	for i, tree := range trees {
+		source := SourceSettings{
+			Name:               getString(tree, "name", fmt.Sprintf("source%v", i+1)),
+			DBConnectionString: getString(tree, "connection_string", settings.DBConnectionString),
+			DBGeometryTable:    getString(tree, "geometry_table", settings.DBGeometryTable),
+			DBGeometryColumn:   getString(tree, "geometry_column", settings.DBGeometryColumn),
+			DBInstanceName:     getString(tree, "instance_name", settings.DBInstanceName),
+			DBSourceSRID:       getInt(tree, "source_srid", settings.DBSourceSRID),
+			DBValidFromColumn:  getString(tree, "valid_from_column", settings.DBValidFromColumn),
+			DBValidToColumn:    getString(tree, "valid_to_column", settings.DBValidToColumn),
+			DBPropertyColumns:  getStrings(tree, "property_columns"),
+			DBPropertiesColumn: getString(tree, "properties_column", settings.DBPropertiesColumn),
+			DBLayers:           readLayers(tree.Get("layers"), settings.DBLayers),
+			Layers:             getStrings(tree, "enabled_layers"),
+		}
+		if source.DBPropertyColumns == nil {
+			source.DBPropertyColumns = settings.DBPropertyColumns
+		}
+		if source.DBConnectionString == "" || source.DBGeometryTable == "" || source.DBGeometryColumn == "" {
+			return nil, fmt.Errorf("Error. Source %s should have connection_string, geometry_table and geometry_column.", source.Name)
+		}
+		if names[source.Name] {
+			return nil, fmt.Errorf("Error. Source %s is declared twice.", source.Name)
+		}
+		names[source.Name] = true
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// getStrings returns an optional array of strings or nil if the key is missing
+func getStrings(config *toml.TomlTree, key string) []string {
+	values, ok := config.Get(key).([]interface{})
+	if !ok {
+		return nil
+	}
+	result := []string{}
+	for _, value := range values {
+		if str, ok := value.(string); ok {
+			result = append(result, str)
+		}
+	}
+	return result
+}
+
+// getString returns an optional string value or "def" if the key is missing
+func getString(config *toml.TomlTree, key string, def string) string {
+	if value, ok := config.Get(key).(string); ok {
+		return value
+	}
+	return def
+}
+
+// getInt returns an optional integer value or "def" if the key is missing
+func getInt(config *toml.TomlTree, key string, def int) int {
+	if value, ok := config.Get(key).(int64); ok {
+		return int(value)
+	}
+	return def
+}
+
+// getBool returns an optional boolean value or "def" if the key is missing
+func getBool(config *toml.TomlTree, key string, def bool) bool {
+	if value, ok := config.Get(key).(bool); ok {
+		return value
+	}
+	return def
+}
+
+// readAssets reads [assets] section, each key is a name of an asset store and its value is a path
+func readAssets(section interface{}) map[string]string {
+	assets := map[string]string{}
+	tree, ok := section.(*toml.TomlTree)
+	if !ok {
+		return assets
+	}
+	for _, name := range tree.Keys() {
+		if path, ok := tree.Get(name).(string); ok {
+			assets[name] = path
+		}
+	}
+	return assets
+}
+
+// readLayers reads "layers" section. Each subsection is a layer name, e.g. [database.layers.objects].
+// Layers and options missing in the section are taken from "inherited" ones.
+func readLayers(section interface{}, inherited map[string]LayerSettings) map[string]LayerSettings {
+	layers := map[string]LayerSettings{}
+	for name, layer := range inherited {
+		layers[name] = layer
+	}
+	tree, ok := section.(*toml.TomlTree)
+	if !ok {
+		return layers
+	}
+	for _, name := range tree.Keys() {
+		layerTree, ok := tree.Get(name).(*toml.TomlTree)
+		if !ok {
+			continue
+		}
+		layer := layers[name]
+		if clip, ok := layerTree.Get("clip").(bool); ok {
+			layer.Clip = clip
+		}
+		switch tolerance := layerTree.Get("simplify_tolerance").(type) {
+		case float64:
+			layer.SimplifyTolerance = tolerance
+		case int64:
+			layer.SimplifyTolerance = float64(tolerance)
+		}
+		layers[name] = layer
+	}
+	return layers
+}
+
 // GetSettings returns single instance of the Settings structure.
 // By default it reads configuration from "config.toml" file
 func GetSettings(conf_path *string) (*Settings, error) {
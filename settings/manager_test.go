@@ -0,0 +1,95 @@
+package settings
+
+import (
+	"testing"
+
+	"github.com/pelletier/go-toml"
+	"github.com/stretchr/testify/assert"
+)
+
+func readTestSources(t *testing.T, content string) ([]SourceSettings, error) {
+	config, err := toml.Load(content)
+	assert.Nil(t, err)
+	settings := Settings{
+		DBConnectionString: getString(config, "database.connection_string", ""),
+		DBGeometryTable:    getString(config, "database.geometry_table", ""),
+		DBGeometryColumn:   getString(config, "database.geometry_column", ""),
+		DBLayers:           readLayers(config.Get("database.layers"), nil),
+	}
+	return readSources(config, &settings)
+}
+
+func TestReadSources_Default(t *testing.T) {
+	sources, err := readTestSources(t, `
+[database]
+  connection_string = "dbname=maps"
+  geometry_table = "maps.objects"
+  geometry_column = "geom"
+`)
+	assert.Nil(t, err)
+	assert.Len(t, sources, 1)
+	assert.Equal(t, "default", sources[0].Name)
+	assert.Equal(t, "maps.objects", sources[0].DBGeometryTable)
+
+	_, err = readTestSources(t, `[http]`)
+	assert.NotNil(t, err)
+}
+
+func TestReadSources_Inheritance(t *testing.T) {
+	sources, err := readTestSources(t, `
+[database]
+  connection_string = "dbname=maps"
+  geometry_column = "geom"
+  [database.layers.objects]
+    clip = true
+    simplify_tolerance = 0.5
+  [database.layers.special]
+    simplify_tolerance = 2
+
+[[sources]]
+  name = "planned"
+  geometry_table = "maps.planned"
+  enabled_layers = ["objects"]
+
+[[sources]]
+  geometry_table = "maps.actual"
+  connection_string = "dbname=situation"
+  [sources.layers.objects]
+    simplify_tolerance = 1
+`)
+	assert.Nil(t, err)
+	assert.Len(t, sources, 2)
+	assert.Equal(t, "planned", sources[0].Name)
+	assert.Equal(t, "dbname=maps", sources[0].DBConnectionString)
+	assert.Equal(t, []string{"objects"}, sources[0].Layers)
+	assert.Equal(t, LayerSettings{Clip: true, SimplifyTolerance: 0.5}, sources[0].DBLayers["objects"])
+
+	assert.Equal(t, "source2", sources[1].Name)
+	assert.Equal(t, "dbname=situation", sources[1].DBConnectionString)
+	assert.Equal(t, LayerSettings{Clip: true, SimplifyTolerance: 1}, sources[1].DBLayers["objects"],
+		"options missing in the source are inherited")
+	assert.Equal(t, LayerSettings{SimplifyTolerance: 2}, sources[1].DBLayers["special"])
+}
+
+func TestReadSources_Errors(t *testing.T) {
+	_, err := readTestSources(t, `
+[[sources]]
+  name = "planned"
+  connection_string = "dbname=maps"
+  geometry_table = "maps.planned"
+`)
+	assert.EqualError(t, err, "Error. Source planned should have connection_string, geometry_table and geometry_column.")
+
+	_, err = readTestSources(t, `
+[database]
+  connection_string = "dbname=maps"
+  geometry_table = "maps.objects"
+  geometry_column = "geom"
+
+[[sources]]
+  name = "a"
+[[sources]]
+  name = "a"
+`)
+	assert.EqualError(t, err, "Error. Source a is declared twice.")
+}
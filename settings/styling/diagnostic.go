@@ -0,0 +1,90 @@
+package styling
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pelletier/go-toml"
+)
+
+// Diagnostic is a problem found in a style file. Line and Key are empty if the problem isn't related to a key.
+// Warnings are reported about ignored constructs, styles having only warnings are loaded.
+type Diagnostic struct {
+	File    string
+	Line    int
+	Key     string
+	Message string
+	Warning bool
+}
+
+func (d *Diagnostic) Error() string {
+	result := d.File
+	if d.Line > 0 {
+		result = fmt.Sprintf("%s:%v", result, d.Line)
+	}
+	if d.Key != "" {
+		result = fmt.Sprintf("%s: %s", result, d.Key)
+	}
+	message := d.Message
+	if d.Warning {
+		message = "warning: " + message
+	}
+	if result == "" {
+		return message
+	}
+	return fmt.Sprintf("%s: %s", result, message)
+}
+
+// IsWarning returns true if the error is a warning diagnostic
+func IsWarning(err error) bool {
+	d, ok := err.(*Diagnostic)
+	return ok && d.Warning
+}
+
+// HasErrors returns true if there is at least one error which isn't a warning
+func HasErrors(errs []error) bool {
+	for _, err := range errs {
+		if !IsWarning(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// location is a place in a style file
+type location struct {
+	file string
+	line int
+}
+
+func treeLocation(file string, tree *toml.TomlTree, key string) location {
+	return location{file: file, line: tree.GetPosition(key).Line}
+}
+
+func (l location) errorf(key string, format string, args ...interface{}) error {
+	return &Diagnostic{File: l.file, Line: l.line, Key: key, Message: fmt.Sprintf(format, args...)}
+}
+
+// sortErrors removes duplicated errors and orders diagnostics by file and line
+func sortErrors(errs []error) []error {
+	result := []error{}
+	seen := map[string]bool{}
+	for _, err := range errs {
+		if !seen[err.Error()] {
+			seen[err.Error()] = true
+			result = append(result, err)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		a, aok := result[i].(*Diagnostic)
+		b, bok := result[j].(*Diagnostic)
+		if !aok || !bok {
+			return aok && !bok
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return result
+}
@@ -0,0 +1,155 @@
+package styling
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TerraFactory/tilegenerator/database/entities"
+	"github.com/pelletier/go-toml"
+)
+
+// Filter selects map objects by their data. Empty conditions match everything.
+// Codes ending with "*" are matched as prefixes. Properties match if the object property equals the value
+// or one of values of an array.
+type Filter struct {
+	TypeIDs    []int
+	Codes      []string
+	HasLabel   *bool
+	MinScale   *float64
+	MaxScale   *float64
+	Properties map[string][]interface{}
+}
+
+// Matches returns true if the object satisfies all conditions of the filter
+func (filter *Filter) Matches(object *entities.MapObject) bool {
+	if len(filter.TypeIDs) > 0 && !containsInt(filter.TypeIDs, object.TypeID) {
+		return false
+	}
+	if len(filter.Codes) > 0 && !matchesCode(filter.Codes, object.Code) {
+		return false
+	}
+	if filter.HasLabel != nil && *filter.HasLabel != (object.Label != "") {
+		return false
+	}
+	if filter.MinScale != nil && object.Scale < *filter.MinScale {
+		return false
+	}
+	if filter.MaxScale != nil && object.Scale > *filter.MaxScale {
+		return false
+	}
+	for name, values := range filter.Properties {
+		if !matchesProperty(object.Properties, name, values) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsInt(list []int, value int) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesCode(codes []string, code string) bool {
+	for _, c := range codes {
+		if strings.HasSuffix(c, "*") {
+			if strings.HasPrefix(code, strings.TrimSuffix(c, "*")) {
+				return true
+			}
+		} else if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesProperty(props entities.Properties, name string, values []interface{}) bool {
+	actual, ok := props.String(name)
+	if !ok {
+		return false
+	}
+	for _, value := range values {
+		if fmt.Sprint(value) == actual {
+			return true
+		}
+	}
+	return false
+}
+
+// toList wraps a single TOML value into a slice, arrays are returned as is
+func toList(value interface{}) []interface{} {
+	if list, ok := value.([]interface{}); ok {
+		return list
+	}
+	return []interface{}{value}
+}
+
+func toFloat(key string, value interface{}) (*float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return &v, nil
+	case int64:
+		f := float64(v)
+		return &f, nil
+	}
+	return nil, fmt.Errorf("%s should be a number, got %v", key, value)
+}
+
+// readFilter reads [filter] section of a style file
+func readFilter(tree *toml.TomlTree) (*Filter, error) {
+	filter := Filter{Properties: map[string][]interface{}{}}
+	for _, key := range tree.Keys() {
+		value := tree.Get(key)
+		switch strings.ToUpper(key) {
+		case "TYPEID":
+			for _, item := range toList(value) {
+				id, ok := item.(int64)
+				if !ok {
+					return nil, fmt.Errorf("TypeID should be an integer or an array of integers, got %v", value)
+				}
+				filter.TypeIDs = append(filter.TypeIDs, int(id))
+			}
+		case "CODE":
+			for _, item := range toList(value) {
+				code, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("Code should be a string or an array of strings, got %v", value)
+				}
+				filter.Codes = append(filter.Codes, code)
+			}
+		case "HASLABEL":
+			hasLabel, ok := value.(bool)
+			if !ok {
+				return nil, fmt.Errorf("HasLabel should be a boolean, got %v", value)
+			}
+			filter.HasLabel = &hasLabel
+		case "MINSCALE":
+			scale, err := toFloat(key, value)
+			if err != nil {
+				return nil, err
+			}
+			filter.MinScale = scale
+		case "MAXSCALE":
+			scale, err := toFloat(key, value)
+			if err != nil {
+				return nil, err
+			}
+			filter.MaxScale = scale
+		case "PROPERTIES":
+			props, ok := value.(*toml.TomlTree)
+			if !ok {
+				return nil, fmt.Errorf("properties should be a table, got %v", value)
+			}
+			for _, name := range props.Keys() {
+				filter.Properties[name] = toList(props.Get(name))
+			}
+		default:
+			return nil, fmt.Errorf("Unknown filter key %s", key)
+		}
+	}
+	return &filter, nil
+}
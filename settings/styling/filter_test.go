@@ -0,0 +1,65 @@
+package styling
+
+import (
+	"testing"
+
+	"github.com/TerraFactory/tilegenerator/database/entities"
+	"github.com/pelletier/go-toml"
+	"github.com/stretchr/testify/assert"
+)
+
+func readTestFilter(t *testing.T, content string) *Filter {
+	tree, err := toml.Load(content)
+	assert.Nil(t, err)
+	filter, err := readFilter(tree)
+	assert.Nil(t, err)
+	return filter
+}
+
+func TestFilter_Matches(t *testing.T) {
+	filter := readTestFilter(t, `
+		TypeID = [47, 74]
+		Code = ["1210620*", "1000000004"]
+		HasLabel = true
+		MinScale = 1
+		[properties]
+		  category = ["airbase", "heliport"]
+	`)
+
+	object := entities.MapObject{
+		TypeID:     47,
+		Code:       "121062001002",
+		Label:      "Base",
+		Scale:      1.5,
+		Properties: entities.Properties{"category": "airbase"},
+	}
+	assert.True(t, filter.Matches(&object))
+
+	object.Code = "1000000004"
+	assert.True(t, filter.Matches(&object), "exact code should match")
+
+	object.Code = "1000000002"
+	assert.False(t, filter.Matches(&object), "code is neither in the list nor has the prefix")
+
+	object.Code = "121062001002"
+	object.TypeID = 48
+	assert.False(t, filter.Matches(&object))
+
+	object.TypeID = 74
+	object.Label = ""
+	assert.False(t, filter.Matches(&object), "object without label should not match")
+
+	object.Label = "Base"
+	object.Properties = entities.Properties{"category": "port"}
+	assert.False(t, filter.Matches(&object))
+}
+
+func TestReadFilter_Errors(t *testing.T) {
+	tree, _ := toml.Load(`TypeID = "47"`)
+	_, err := readFilter(tree)
+	assert.NotNil(t, err)
+
+	tree, _ = toml.Load(`Colour = "red"`)
+	_, err = readFilter(tree)
+	assert.NotNil(t, err, "unknown keys should be reported")
+}
@@ -0,0 +1,228 @@
+package styling
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+)
+
+// definition is a style or a fragment read from a file before inheritance is resolved.
+// Styles have "Name", fragments have "Fragment" instead and contain only primitives and includes.
+type definition struct {
+	file     string
+	tree     *toml.TomlTree
+	name     string
+	fragment bool
+}
+
+// at returns location of the key of the definition
+func (def *definition) at(key string) location {
+	return treeLocation(def.file, def.tree, key)
+}
+
+// rawPrimitive is a primitive before creation, with locations of its parameters
+type rawPrimitive struct {
+	params map[string]interface{}
+	keys   map[string]location
+	at     location
+}
+
+func newRawPrimitive(file string, tree *toml.TomlTree) rawPrimitive {
+	p := rawPrimitive{params: tree.ToMap(), keys: map[string]location{}, at: location{file: file}}
+	for _, key := range tree.Keys() {
+		loc := treeLocation(file, tree, key)
+		p.keys[key] = loc
+		if p.at.line == 0 || (loc.line > 0 && loc.line < p.at.line) {
+			p.at = loc
+		}
+	}
+	return p
+}
+
+func (p rawPrimitive) copy() rawPrimitive {
+	result := rawPrimitive{params: map[string]interface{}{}, keys: map[string]location{}, at: p.at}
+	for key, value := range p.params {
+		result.params[key] = value
+	}
+	for key, loc := range p.keys {
+		result.keys[key] = loc
+	}
+	return result
+}
+
+func (p rawPrimitive) id() string {
+	id, _ := p.params["Id"].(string)
+	return id
+}
+
+// flatStyle is a style with resolved "Extends" and "[[include]]", its primitives are raw parameters
+type flatStyle struct {
+	at             location
+	name           string
+	geometryType   string
+	geometryTypeAt location
+	priority       int64
+	isElse         bool
+	abstract       bool
+	filter         *toml.TomlTree
+	filterAt       location
+	parsedFilter   *Filter
+	primitives     []rawPrimitive
+}
+
+// resolver flattens styles which extend other styles and include fragments
+type resolver struct {
+	styles    map[string]*definition
+	fragments map[string]*definition
+	resolved  map[string]*flatStyle
+}
+
+func newResolver(definitions []*definition) (*resolver, []error) {
+	r := resolver{
+		styles:    map[string]*definition{},
+		fragments: map[string]*definition{},
+		resolved:  map[string]*flatStyle{},
+	}
+	errs := []error{}
+	for _, def := range definitions {
+		target := r.styles
+		kind := "Name"
+		if def.fragment {
+			target = r.fragments
+			kind = "Fragment"
+		}
+		if previous, ok := target[def.name]; ok {
+			errs = append(errs, def.at(kind).errorf(kind, "%s is already declared in %s", def.name, previous.file))
+			continue
+		}
+		target[def.name] = def
+	}
+	return &r, errs
+}
+
+func inStack(stack []string, name string) bool {
+	for _, s := range stack {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveStyle returns the style with all parents and fragments merged into it
+func (r *resolver) resolveStyle(name string, stack []string) (*flatStyle, error) {
+	if flat, ok := r.resolved[name]; ok {
+		return flat, nil
+	}
+	def, ok := r.styles[name]
+	if !ok {
+		return nil, &Diagnostic{Message: fmt.Sprintf("Style %s is not found", name)}
+	}
+	stack = append(stack, name)
+
+	flat := flatStyle{at: location{file: def.file}, name: name}
+	if parentName, ok := def.tree.Get("Extends").(string); ok {
+		if inStack(stack, parentName) {
+			return nil, def.at("Extends").errorf("Extends", "Extends cycle: %s -> %s", strings.Join(stack, " -> "), parentName)
+		}
+		if _, ok := r.styles[parentName]; !ok {
+			return nil, def.at("Extends").errorf("Extends", "Style %s is not found", parentName)
+		}
+		parent, err := r.resolveStyle(parentName, stack)
+		if err != nil {
+			return nil, err
+		}
+		flat.geometryType, flat.geometryTypeAt = parent.geometryType, parent.geometryTypeAt
+		flat.filter, flat.filterAt = parent.filter, parent.filterAt
+		flat.priority = parent.priority
+		flat.isElse = parent.isElse
+		flat.primitives = mergePrimitives(nil, parent.primitives)
+	}
+
+	if geometryType, ok := def.tree.Get("GeometryType").(string); ok {
+		flat.geometryType, flat.geometryTypeAt = geometryType, def.at("GeometryType")
+	}
+	if priority, ok := def.tree.Get("Priority").(int64); ok {
+		flat.priority = priority
+	}
+	if isElse, ok := def.tree.Get("Else").(bool); ok {
+		flat.isElse = isElse
+	}
+	if abstract, ok := def.tree.Get("Abstract").(bool); ok {
+		flat.abstract = abstract
+	}
+	if filter, ok := def.tree.Get("filter").(*toml.TomlTree); ok {
+		flat.filter, flat.filterAt = filter, def.at("filter")
+	}
+
+	own, err := r.ownPrimitives(def, nil)
+	if err != nil {
+		return nil, err
+	}
+	flat.primitives = mergePrimitives(flat.primitives, own)
+
+	r.resolved[name] = &flat
+	return &flat, nil
+}
+
+// ownPrimitives returns primitives of included fragments followed by primitives declared in the definition
+func (r *resolver) ownPrimitives(def *definition, stack []string) ([]rawPrimitive, error) {
+	result := []rawPrimitive{}
+
+	includes, _ := def.tree.Get("include").([]*toml.TomlTree)
+	for _, include := range includes {
+		at := treeLocation(def.file, include, "Fragment")
+		fragmentName, ok := include.Get("Fragment").(string)
+		if !ok {
+			return nil, at.errorf("include", "[[include]] should have a Fragment name")
+		}
+		if inStack(stack, fragmentName) {
+			return nil, at.errorf("Fragment", "Include cycle: %s -> %s", strings.Join(stack, " -> "), fragmentName)
+		}
+		fragment, ok := r.fragments[fragmentName]
+		if !ok {
+			return nil, at.errorf("Fragment", "Fragment %s is not found", fragmentName)
+		}
+		primitives, err := r.ownPrimitives(fragment, append(stack, fragmentName))
+		if err != nil {
+			return nil, err
+		}
+		result = mergePrimitives(result, primitives)
+	}
+
+	trees, _ := def.tree.Get("primitives").([]*toml.TomlTree)
+	primitives := []rawPrimitive{}
+	for _, tree := range trees {
+		primitives = append(primitives, newRawPrimitive(def.file, tree))
+	}
+	return mergePrimitives(result, primitives), nil
+}
+
+// mergePrimitives appends "own" primitives to "base" ones. Own primitive with the same "Id" as a base primitive
+// overrides parameters of the base primitive instead of being appended.
+func mergePrimitives(base, own []rawPrimitive) []rawPrimitive {
+	result := []rawPrimitive{}
+	for _, p := range base {
+		result = append(result, p.copy())
+	}
+	for _, p := range own {
+		merged := false
+		if id := p.id(); id != "" {
+			for _, existing := range result {
+				if existing.id() == id {
+					for key, value := range p.params {
+						existing.params[key] = value
+						existing.keys[key] = p.keys[key]
+					}
+					merged = true
+					break
+				}
+			}
+		}
+		if !merged {
+			result = append(result, p.copy())
+		}
+	}
+	return result
+}
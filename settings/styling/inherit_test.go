@@ -0,0 +1,105 @@
+package styling
+
+import (
+	"testing"
+
+	"github.com/pelletier/go-toml"
+	"github.com/stretchr/testify/assert"
+)
+
+func testDefinition(t *testing.T, file string, content string) *definition {
+	tree, err := toml.Load(content)
+	assert.Nil(t, err)
+	if name, ok := tree.Get("Name").(string); ok {
+		return &definition{file: file, tree: tree, name: name}
+	}
+	return &definition{file: file, tree: tree, name: tree.Get("Fragment").(string), fragment: true}
+}
+
+func TestResolver_Extends(t *testing.T) {
+	r, errs := newResolver([]*definition{
+		testDefinition(t, "base.toml", `
+			Name = "mil/base"
+			GeometryType = "POINT"
+			Abstract = true
+			Priority = 5
+			[[primitives]]
+			  Type = "IMAGE"
+			  Id = "icon"
+			  Width = 24
+			  Href = "base.png"
+		`),
+		testDefinition(t, "label.toml", `
+			Fragment = "label"
+			[[primitives]]
+			  Type = "TEXT"
+			  Content = "${label}"
+		`),
+		testDefinition(t, "airbase.toml", `
+			Name = "mil/airbase"
+			Extends = "mil/base"
+			Priority = 10
+			[[include]]
+			  Fragment = "label"
+			[[primitives]]
+			  Id = "icon"
+			  Href = "airbase.png"
+		`),
+	})
+	assert.Empty(t, errs)
+
+	flat, err := r.resolveStyle("mil/airbase", nil)
+	assert.Nil(t, err)
+	assert.False(t, flat.abstract)
+	assert.Equal(t, "POINT", flat.geometryType)
+	assert.Equal(t, int64(10), flat.priority)
+	assert.Len(t, flat.primitives, 2)
+	assert.Equal(t, "airbase.png", flat.primitives[0].params["Href"])
+	assert.Equal(t, int64(24), flat.primitives[0].params["Width"])
+	assert.Equal(t, "TEXT", flat.primitives[1].params["Type"])
+
+	base, err := r.resolveStyle("mil/base", nil)
+	assert.Nil(t, err)
+	assert.True(t, base.abstract)
+	assert.Equal(t, "base.png", base.primitives[0].params["Href"])
+}
+
+func TestResolver_Cycles(t *testing.T) {
+	r, _ := newResolver([]*definition{
+		testDefinition(t, "a.toml", `
+			Name = "a"
+			Extends = "b"
+		`),
+		testDefinition(t, "b.toml", `
+			Name = "b"
+			Extends = "a"
+		`),
+		testDefinition(t, "c.toml", `
+			Name = "c"
+			GeometryType = "POINT"
+			[[include]]
+			  Fragment = "f1"
+		`),
+		testDefinition(t, "f1.toml", `
+			Fragment = "f1"
+			[[include]]
+			  Fragment = "f2"
+		`),
+		testDefinition(t, "f2.toml", `
+			Fragment = "f2"
+			[[include]]
+			  Fragment = "f1"
+		`),
+	})
+
+	_, err := r.resolveStyle("a", nil)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Extends cycle: a -> b -> a")
+
+	_, err = r.resolveStyle("c", nil)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Include cycle: f1 -> f2 -> f1")
+
+	_, err = r.resolveStyle("unknown", nil)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,548 @@
+package styling
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Size of icons in pixels when "icon-size" is 1
+const mapLibreIconSize = 24
+
+// Tokens of "text-field" and "icon-image", e.g. "{name}"
+var mapLibreToken = regexp.MustCompile(`\{([^}]+)\}`)
+
+// mapLibreStyle is a subset of MapLibre (Mapbox GL) style JSON which can be imported
+type mapLibreStyle struct {
+	Version int             `json:"version"`
+	Sprite  string          `json:"sprite"`
+	Layers  []mapLibreLayer `json:"layers"`
+}
+
+type mapLibreLayer struct {
+	ID      string                 `json:"id"`
+	Type    string                 `json:"type"`
+	MinZoom *float64               `json:"minzoom"`
+	MaxZoom *float64               `json:"maxzoom"`
+	Filter  interface{}            `json:"filter"`
+	Layout  map[string]interface{} `json:"layout"`
+	Paint   map[string]interface{} `json:"paint"`
+}
+
+// mapLibreProperty is a primitive parameter which a paint or layout property is converted to.
+// Zoomable properties may be functions of zoom.
+type mapLibreProperty struct {
+	param    string
+	zoomable bool
+}
+
+// Supported properties of layers. Symbol layers are split into "icon" and "text" primitives.
+var mapLibreProperties = map[string]map[string]mapLibreProperty{
+	"circle": {
+		"circle-radius":         {"Radius", true},
+		"circle-color":          {"Fill", false},
+		"circle-opacity":        {"FillOpacity", false},
+		"circle-stroke-color":   {"Stroke", false},
+		"circle-stroke-width":   {"StrokeWidth", true},
+		"circle-stroke-opacity": {"StrokeOpacity", false},
+	},
+	"line": {
+		"line-color":     {"Stroke", false},
+		"line-width":     {"StrokeWidth", true},
+		"line-opacity":   {"StrokeOpacity", false},
+		"line-dasharray": {"DashArray", false},
+		"line-cap":       {"LineCap", false},
+		"line-join":      {"LineJoin", false},
+	},
+	"fill": {
+		"fill-color":         {"Fill", false},
+		"fill-opacity":       {"FillOpacity", false},
+		"fill-outline-color": {"Stroke", false},
+	},
+	"icon": {
+		"icon-image":  {"Href", false},
+		"icon-size":   {"Width", true},
+		"icon-rotate": {"Rotate", false},
+	},
+	"text": {
+		"text-field":       {"Content", false},
+		"text-size":        {"Size", true},
+		"text-font":        {"FontFamily", false},
+		"text-color":       {"Color", false},
+		"text-opacity":     {"Opacity", false},
+		"text-halo-color":  {"HaloColor", false},
+		"text-halo-width":  {"HaloWidth", false},
+		"text-anchor":      {"Anchor", false},
+		"text-max-width":   {"MaxWidth", false},
+		"text-line-height": {"LineHeight", false},
+	},
+}
+
+// Values of "text-anchor" which can be converted into anchors of TEXT primitive
+var mapLibreTextAnchors = map[string]string{"center": "middle", "left": "start", "right": "end"}
+
+// Primitives of layers with their default parameters. MapLibre defaults differ from defaults of primitives.
+var mapLibrePrimitives = map[string]func() map[string]interface{}{
+	"circle": func() map[string]interface{} {
+		return map[string]interface{}{"Type": "CIRCLE", "Fill": "#000000", "StrokeWidth": 0.0}
+	},
+	"line": func() map[string]interface{} {
+		return map[string]interface{}{"Type": "LINE", "Stroke": "#000000"}
+	},
+	"fill": func() map[string]interface{} {
+		return map[string]interface{}{"Type": "POLYGON", "Fill": "#000000", "Stroke": "none"}
+	},
+	"icon": func() map[string]interface{} {
+		return map[string]interface{}{"Type": "IMAGE", "Width": float64(mapLibreIconSize), "Height": float64(mapLibreIconSize)}
+	},
+	"text": func() map[string]interface{} {
+		return map[string]interface{}{"Type": "TEXT", "Size": 16.0}
+	},
+}
+
+// mapLibreImporter converts layers of a MapLibre style file into styles and collects problems
+type mapLibreImporter struct {
+	file string
+	errs []error
+}
+
+func (im *mapLibreImporter) warnf(key string, format string, args ...interface{}) {
+	im.errs = append(im.errs, &Diagnostic{File: im.file, Key: key, Message: fmt.Sprintf(format, args...), Warning: true})
+}
+
+// readMapLibreFile reads circle, line, fill and symbol layers of a MapLibre style JSON as styles.
+// Each layer is a style named by the layer id, later layers have higher priority. Priorities of imported styles
+// are negative, so styles of TOML files with the default priority are checked first.
+// An object is rendered by one style only, unlike stacked MapLibre layers, so shadowed layers are reported.
+// Unsupported layers, filters and properties are reported as warnings and skipped.
+func readMapLibreFile(filename string) ([]*flatStyle, []error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, []error{&Diagnostic{File: filename, Message: err.Error()}}
+	}
+	var style mapLibreStyle
+	if err := json.Unmarshal(data, &style); err != nil {
+		return nil, []error{&Diagnostic{File: filename, Message: err.Error()}}
+	}
+
+	im := mapLibreImporter{file: filename}
+	if style.Sprite != "" {
+		im.warnf("sprite", "Sprites are not supported, icon-image is used as an image href")
+	}
+	flats := []*flatStyle{}
+	for i, layer := range style.Layers {
+		if flat := im.importLayer(layer, int64(i-len(style.Layers)), fmt.Sprintf("layers[%v]", i)); flat != nil {
+			flats = append(flats, flat)
+		}
+	}
+	im.warnShadowed(flats)
+	return flats, im.errs
+}
+
+// warnShadowed reports layers which are never rendered: a later layer of the same geometry type
+// without a filter or with the same filter matches all their objects first
+func (im *mapLibreImporter) warnShadowed(flats []*flatStyle) {
+	for i, earlier := range flats {
+		for _, later := range flats[i+1:] {
+			if !strings.EqualFold(earlier.geometryType, later.geometryType) {
+				continue
+			}
+			if reflect.DeepEqual(later.parsedFilter, &Filter{}) || reflect.DeepEqual(later.parsedFilter, earlier.parsedFilter) {
+				im.warnf("layers."+earlier.name, "Layer is shadowed by layer %s, an object is rendered by one style only", later.name)
+				break
+			}
+		}
+	}
+}
+
+func (im *mapLibreImporter) importLayer(layer mapLibreLayer, priority int64, key string) *flatStyle {
+	if layer.ID == "" {
+		im.warnf(key, "Layer without id is skipped")
+		return nil
+	}
+	key = "layers." + layer.ID
+
+	flat := flatStyle{at: location{file: im.file}, name: layer.ID, priority: priority, parsedFilter: &Filter{}}
+	switch layer.Type {
+	case "circle", "symbol":
+		flat.geometryType = "POINT"
+	case "line":
+		flat.geometryType = "LINESTRING"
+	case "fill":
+		flat.geometryType = "POLYGON"
+	default:
+		im.warnf(key, "Layer type %s is not supported, the layer is skipped", layer.Type)
+		return nil
+	}
+	if layer.Layout["visibility"] == "none" {
+		return nil
+	}
+
+	if layer.Filter != nil {
+		geometryType, ok := im.filter(key+".filter", layer.Filter, flat.parsedFilter)
+		if !ok {
+			return nil
+		}
+		if geometryType != "" {
+			flat.geometryType = geometryType
+		}
+	}
+
+	for _, params := range im.primitives(key, layer) {
+		if layer.MinZoom != nil {
+			params["MinZoom"] = int64(math.Floor(*layer.MinZoom))
+		}
+		if layer.MaxZoom != nil {
+			// maxzoom is exclusive in MapLibre
+			params["MaxZoom"] = int64(math.Ceil(*layer.MaxZoom)) - 1
+		}
+		flat.primitives = append(flat.primitives, rawPrimitive{params: params, keys: map[string]location{}, at: flat.at})
+	}
+	return &flat
+}
+
+// primitives converts paint and layout properties of the layer into parameters of primitives
+func (im *mapLibreImporter) primitives(key string, layer mapLibreLayer) []map[string]interface{} {
+	kinds := []string{layer.Type}
+	if layer.Type == "symbol" {
+		kinds = []string{}
+		if _, ok := layer.Layout["icon-image"]; ok {
+			kinds = append(kinds, "icon")
+		}
+		if _, ok := layer.Layout["text-field"]; ok {
+			kinds = append(kinds, "text")
+		}
+	}
+	result := map[string]map[string]interface{}{}
+	for _, kind := range kinds {
+		result[kind] = mapLibrePrimitives[kind]()
+	}
+
+	properties := map[string]interface{}{}
+	for name, value := range layer.Paint {
+		properties["paint."+name] = value
+	}
+	for name, value := range layer.Layout {
+		if name != "visibility" {
+			properties["layout."+name] = value
+		}
+	}
+	names := []string{}
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		propertyKey := key + "." + name
+		property := name[strings.Index(name, ".")+1:]
+		kind := layer.Type
+		if kind == "symbol" {
+			kind = strings.SplitN(property, "-", 2)[0]
+		}
+		spec, supported := mapLibreProperties[kind][property]
+		params, ok := result[kind]
+		if !supported || !ok {
+			im.warnf(propertyKey, "Property is not supported and ignored")
+			continue
+		}
+		if property == "text-font" {
+			// Array of font names is a constant, not an expression
+			if fonts, ok := properties[name].([]interface{}); ok {
+				names := []string{}
+				for _, font := range fonts {
+					names = append(names, fmt.Sprint(font))
+				}
+				params[spec.param] = strings.Join(names, ", ")
+				continue
+			}
+		}
+		value, ok := im.value(propertyKey, properties[name], spec.zoomable)
+		if !ok {
+			continue
+		}
+		switch property {
+		case "text-anchor":
+			anchor, ok := mapLibreTextAnchors[fmt.Sprint(value)]
+			if !ok {
+				im.warnf(propertyKey, "Anchor %v is not supported, the property is ignored", value)
+				continue
+			}
+			value = anchor
+		case "text-field", "icon-image":
+			if value, ok = im.template(propertyKey, value); !ok {
+				continue
+			}
+		case "icon-size":
+			value = scaleParam(value, mapLibreIconSize)
+			params["Height"] = value
+		}
+		params[spec.param] = value
+	}
+
+	// Dashes are measured in line widths
+	if params, ok := result["line"]; ok && params["DashArray"] != nil {
+		switch width := params["StrokeWidth"].(type) {
+		case float64:
+			params["DashArray"] = scaleParam(params["DashArray"], width)
+		case []interface{}:
+			im.warnf(key+".paint.line-dasharray", "Dashes are not scaled by zoom dependent line-width")
+		}
+	}
+
+	// Maximal width of text is measured in font sizes
+	if params, ok := result["text"]; ok && params["MaxWidth"] != nil {
+		switch size := params["Size"].(type) {
+		case float64:
+			params["MaxWidth"] = scaleParam(params["MaxWidth"], size)
+		case []interface{}:
+			im.warnf(key+".layout.text-max-width", "Width is not scaled by zoom dependent text-size")
+		}
+	}
+
+	primitives := []map[string]interface{}{}
+	for _, kind := range kinds {
+		primitives = append(primitives, result[kind])
+	}
+	return primitives
+}
+
+// value converts a constant or a zoom function into a primitive parameter. Zoom functions are converted into
+// arrays of [zoom, value] pairs, exponential interpolation is approximated by linear one.
+func (im *mapLibreImporter) value(key string, value interface{}, zoomable bool) (interface{}, bool) {
+	switch v := value.(type) {
+	case float64, string, bool:
+		return v, true
+	case map[string]interface{}:
+		stops, ok := v["stops"].([]interface{})
+		if zoomable && ok && v["property"] == nil {
+			if base, ok := v["base"].(float64); ok && base != 1 {
+				im.warnf(key, "Exponential function is approximated by linear interpolation")
+			}
+			return stops, true
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			break
+		}
+		op, ok := v[0].(string)
+		if !ok {
+			// Array of numbers, e.g. line-dasharray
+			return v, true
+		}
+		switch {
+		case op == "literal" && len(v) == 2:
+			return v[1], true
+		case op == "get" && len(v) == 2:
+			if name, ok := v[1].(string); ok && !zoomable {
+				return "{" + name + "}", true
+			}
+		case op == "interpolate" && zoomable && len(v) >= 5 && len(v)%2 == 1:
+			return im.interpolation(key, v)
+		}
+	}
+	im.warnf(key, "Value %v is not supported, the property is ignored", mapLibreString(value))
+	return nil, false
+}
+
+// interpolation converts ["interpolate", ["linear"], ["zoom"], z1, v1, z2, v2, ...] into stops
+func (im *mapLibreImporter) interpolation(key string, expr []interface{}) (interface{}, bool) {
+	interpolation, _ := expr[1].([]interface{})
+	input, _ := expr[2].([]interface{})
+	if len(input) != 1 || input[0] != "zoom" || len(interpolation) == 0 {
+		im.warnf(key, "Only interpolation by zoom is supported, the property is ignored")
+		return nil, false
+	}
+	if interpolation[0] != "linear" {
+		im.warnf(key, "%v interpolation is approximated by linear one", interpolation[0])
+	}
+	stops := []interface{}{}
+	for i := 3; i < len(expr); i += 2 {
+		stops = append(stops, []interface{}{expr[i], expr[i+1]})
+	}
+	return stops, true
+}
+
+// template converts "{name}" tokens into "${prop.name}" templates of primitives, "{label}" is the object label
+func (im *mapLibreImporter) template(key string, value interface{}) (interface{}, bool) {
+	str, ok := value.(string)
+	if !ok {
+		im.warnf(key, "Value %v is not supported, the property is ignored", mapLibreString(value))
+		return nil, false
+	}
+	return mapLibreToken.ReplaceAllStringFunc(str, func(token string) string {
+		name := mapLibreToken.FindStringSubmatch(token)[1]
+		if name == "label" {
+			return "${label}"
+		}
+		return "${prop." + name + "}"
+	}), true
+}
+
+// filter adds conditions of a MapLibre filter to the "filter". Legacy and expression syntaxes are supported
+// for "all", "==", "in", "match", "has", "!has" and scale comparisons. Returns geometry type if the filter selects it.
+func (im *mapLibreImporter) filter(key string, expr interface{}, filter *Filter) (string, bool) {
+	list, _ := expr.([]interface{})
+	op := ""
+	if len(list) > 0 {
+		op, _ = list[0].(string)
+	}
+	var args []interface{}
+	if len(list) > 1 {
+		args = list[1:]
+	}
+
+	switch {
+	case op == "all":
+		geometryType := ""
+		for _, arg := range args {
+			t, ok := im.filter(key, arg, filter)
+			if !ok {
+				return "", false
+			}
+			if t != "" {
+				geometryType = t
+			}
+		}
+		return geometryType, true
+	case op == "==" && len(args) == 2:
+		if name, ok := filterOperand(args[0]); ok {
+			return im.condition(key, expr, name, []interface{}{args[1]}, filter)
+		}
+	case op == "in" && len(args) >= 2:
+		name, ok := filterOperand(args[0])
+		if _, legacy := args[0].(string); ok && legacy {
+			return im.condition(key, expr, name, args[1:], filter)
+		}
+		if literal, isList := args[1].([]interface{}); ok && isList && len(args) == 2 && len(literal) == 2 && literal[0] == "literal" {
+			if values, isList := literal[1].([]interface{}); isList {
+				return im.condition(key, expr, name, values, filter)
+			}
+		}
+	case op == "match" && len(args) == 4 && args[2] == true && args[3] == false:
+		if name, ok := filterOperand(args[0]); ok {
+			values, isList := args[1].([]interface{})
+			if !isList {
+				values = []interface{}{args[1]}
+			}
+			return im.condition(key, expr, name, values, filter)
+		}
+	case (op == "has" || op == "!has") && len(args) == 1 && args[0] == "label":
+		hasLabel := op == "has"
+		filter.HasLabel = &hasLabel
+		return "", true
+	case (op == ">=" || op == ">" || op == "<=" || op == "<") && len(args) == 2:
+		name, ok := filterOperand(args[0])
+		value, isNumber := args[1].(float64)
+		if ok && isNumber && name == "scale" {
+			if len(op) == 1 {
+				im.warnf(key, "Filter %s is approximated by %s=", mapLibreString(expr), op)
+			}
+			if op[0] == '>' {
+				filter.MinScale = &value
+			} else {
+				filter.MaxScale = &value
+			}
+			return "", true
+		}
+	}
+	im.warnf(key, "Filter %s is not supported, the layer is skipped", mapLibreString(expr))
+	return "", false
+}
+
+// condition adds "name is one of values" condition. "type_id" and "code" are object fields, "$type" is a geometry type.
+func (im *mapLibreImporter) condition(key string, expr interface{}, name string, values []interface{}, filter *Filter) (string, bool) {
+	switch name {
+	case "$type":
+		if t, ok := values[0].(string); ok && len(values) == 1 {
+			return t, true
+		}
+	case "type_id":
+		if len(filter.TypeIDs) > 0 {
+			break
+		}
+		for _, value := range values {
+			id, ok := value.(float64)
+			if !ok {
+				return im.unsupportedFilter(key, expr)
+			}
+			filter.TypeIDs = append(filter.TypeIDs, int(id))
+		}
+		return "", true
+	case "code":
+		if len(filter.Codes) > 0 {
+			break
+		}
+		for _, value := range values {
+			filter.Codes = append(filter.Codes, fmt.Sprint(value))
+		}
+		return "", true
+	default:
+		if filter.Properties == nil {
+			filter.Properties = map[string][]interface{}{}
+		}
+		if _, ok := filter.Properties[name]; ok {
+			break
+		}
+		filter.Properties[name] = values
+		return "", true
+	}
+	return im.unsupportedFilter(key, expr)
+}
+
+func (im *mapLibreImporter) unsupportedFilter(key string, expr interface{}) (string, bool) {
+	im.warnf(key, "Filter %s is not supported, the layer is skipped", mapLibreString(expr))
+	return "", false
+}
+
+// filterOperand returns a name of a property compared by a filter: "name" in legacy syntax or ["get", "name"]
+func filterOperand(arg interface{}) (string, bool) {
+	switch v := arg.(type) {
+	case string:
+		return v, true
+	case []interface{}:
+		if len(v) == 1 && v[0] == "geometry-type" {
+			return "$type", true
+		}
+		if len(v) == 2 && v[0] == "get" {
+			name, ok := v[1].(string)
+			return name, ok
+		}
+	}
+	return "", false
+}
+
+// scaleParam multiplies a number, an array of numbers or values of [zoom, value] pairs by "factor"
+func scaleParam(value interface{}, factor float64) interface{} {
+	switch v := value.(type) {
+	case float64:
+		return v * factor
+	case []interface{}:
+		result := []interface{}{}
+		for _, item := range v {
+			if pair, ok := item.([]interface{}); ok && len(pair) == 2 {
+				result = append(result, []interface{}{pair[0], scaleParam(pair[1], factor)})
+			} else {
+				result = append(result, scaleParam(item, factor))
+			}
+		}
+		return result
+	}
+	return value
+}
+
+func mapLibreString(value interface{}) string {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return fmt.Sprint(value)
+	}
+	return strings.TrimSpace(buffer.String())
+}
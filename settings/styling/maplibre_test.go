@@ -0,0 +1,92 @@
+package styling
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readTestMapLibre(t *testing.T, content string) ([]*flatStyle, []error) {
+	file, err := ioutil.TempFile("", "style*.json")
+	assert.Nil(t, err)
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(content)
+	assert.Nil(t, err)
+	file.Close()
+	return readMapLibreFile(file.Name())
+}
+
+func TestReadMapLibreFile(t *testing.T) {
+	flats, errs := readTestMapLibre(t, `{
+		"version": 8,
+		"layers": [
+			{"id": "background", "type": "background"},
+			{"id": "roads", "type": "line", "maxzoom": 15,
+			 "filter": ["all", ["==", "$type", "LineString"], ["in", ["get", "class"], ["literal", ["road", "path"]]]],
+			 "paint": {"line-width": ["interpolate", ["linear"], ["zoom"], 5, 1, 10, 4], "line-color": "red", "line-blur": 1}},
+			{"id": "posts", "type": "circle", "filter": ["==", "type_id", 47],
+			 "paint": {"circle-radius": {"stops": [[5, 2], [10, 6]]}, "circle-color": "#00f"}},
+			{"id": "names", "type": "symbol", "filter": [">=", ["get", "scale"], 2],
+			 "layout": {"text-field": ["get", "name"], "icon-image": "{category}.png", "icon-size": 2}},
+			{"id": "any", "type": "fill", "filter": ["any", ["==", "a", 1], ["==", "b", 2]]}
+		]
+	}`)
+
+	messages := []string{}
+	for _, err := range errs {
+		assert.True(t, IsWarning(err))
+		messages = append(messages, err.(*Diagnostic).Key)
+	}
+	assert.Equal(t, []string{"layers.background", "layers.roads.paint.line-blur", "layers.any.filter"}, messages)
+	assert.Len(t, flats, 3)
+
+	roads := flats[0]
+	assert.Equal(t, "LineString", roads.geometryType)
+	assert.Equal(t, int64(-4), roads.priority, "imported styles have lower priority than TOML styles")
+	assert.Equal(t, []interface{}{"road", "path"}, roads.parsedFilter.Properties["class"])
+	assert.Equal(t, "red", roads.primitives[0].params["Stroke"])
+	assert.Equal(t, int64(14), roads.primitives[0].params["MaxZoom"])
+
+	posts := flats[1]
+	assert.Equal(t, []int{47}, posts.parsedFilter.TypeIDs)
+
+	names := flats[2]
+	assert.Equal(t, 2.0, *names.parsedFilter.MinScale)
+	assert.Len(t, names.primitives, 2)
+	assert.Equal(t, "${prop.category}.png", names.primitives[0].params["Href"])
+	assert.Equal(t, 48.0, names.primitives[0].params["Height"])
+	assert.Equal(t, "${prop.name}", names.primitives[1].params["Content"])
+
+	for _, flat := range flats {
+		_, errs := buildStyle(flat)
+		assert.Empty(t, errs)
+	}
+}
+
+func TestReadMapLibreFile_Shadowed(t *testing.T) {
+	flats, errs := readTestMapLibre(t, `{
+		"version": 8,
+		"layers": [
+			{"id": "casing", "type": "line", "paint": {"line-width": 4}},
+			{"id": "large", "type": "circle", "filter": [">", "scale", 2]},
+			{"id": "road", "type": "line", "filter": ["==", "class", "road"]},
+			{"id": "points", "type": "circle"},
+			{"id": "path", "type": "line", "filter": ["==", "class", "road"]}
+		]
+	}`)
+	assert.Len(t, flats, 5)
+	assert.True(t, flats[0].priority < flats[4].priority)
+	assert.True(t, flats[4].priority < 0)
+
+	messages := []string{}
+	for _, err := range errs {
+		messages = append(messages, err.(*Diagnostic).Key+": "+err.(*Diagnostic).Message)
+	}
+	assert.Equal(t, []string{
+		`layers.large.filter: Filter [">","scale",2] is approximated by >=`,
+		"layers.large: Layer is shadowed by layer points, an object is rendered by one style only",
+		"layers.road: Layer is shadowed by layer path, an object is rendered by one style only",
+	}, messages)
+}
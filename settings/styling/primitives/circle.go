@@ -0,0 +1,57 @@
+package primitives
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TerraFactory/svgo"
+	"github.com/TerraFactory/tilegenerator/database/entities"
+)
+
+// CirclePrimitive draws a circle with radius in pixels around a point
+type CirclePrimitive struct {
+	Paint
+	Radius ZoomValue
+}
+
+func (circle CirclePrimitive) Render(canvas *svg.SVG, object *entities.MapObject, zoom int) {
+	point, err := object.Geometry.AsPoint()
+	if err != nil {
+		logf(canvas, "Can't render CIRCLE for object %v: %v \n", object.ID, err)
+		return
+	}
+
+	paint := circle.forObject(object)
+	patternID := paint.renderPattern(canvas, object, zoom)
+	x, y, radius := int(point.Coordinates.X), int(point.Coordinates.Y), int(circle.Radius.At(zoom))
+	style := paint.style(patternID, true, zoom)
+	if defs := DefsOf(canvas); defs != nil {
+		id, _ := defs.Define(fmt.Sprintf("circle|%v|%s", radius, style), func(target *svg.SVG, id string) error {
+			target.Circle(0, 0, radius, style, fmt.Sprintf(`id="%s"`, id))
+			return nil
+		})
+		canvas.Use(x, y, "#"+id)
+		return
+	}
+	canvas.Circle(x, y, radius, style)
+}
+
+// Size returns size of the circle with its stroke, labels are placed around it
+func (circle CirclePrimitive) Size(canvas *svg.SVG, object *entities.MapObject, zoom int) (float64, float64) {
+	diameter := circle.Radius.At(zoom)*2 + circle.StrokeWidth.At(zoom)
+	return diameter, diameter
+}
+
+func NewCirclePrimitive(params *map[string]interface{}) (CirclePrimitive, error) {
+	circle := CirclePrimitive{Paint: newPaint(), Radius: Constant(5)}
+	for key, value := range *params {
+		handled, err := circle.setParam(key, value)
+		if !handled && strings.ToUpper(key) == "RADIUS" {
+			circle.Radius, err = paramZoomValue(key, value)
+		}
+		if err != nil {
+			return circle, err
+		}
+	}
+	return circle, nil
+}
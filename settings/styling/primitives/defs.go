@@ -0,0 +1,100 @@
+package primitives
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+
+	"github.com/TerraFactory/svgo"
+	"github.com/TerraFactory/tilegenerator/symbology"
+)
+
+// Logger writes problems of rendering, e.g. *log.Logger
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Defs collects images and shapes repeated in a tile. Each of them is written once into <defs>
+// and drawn with <use>. Defs is the writer of the canvas objects are rendered on, so primitives find it by the canvas.
+// It also carries the rendering context of the canvas:
+// Load reads images of IMAGE primitives, assets.Load is used if it is nil;
+// Codes map object codes of SYMBOL primitives to SIDC;
+// Debug adds reasons of failures to the SVG output: comments and "data-error" attributes of placeholders;
+// Logger writes problems of primitives, the standard logger is used if it is nil.
+type Defs struct {
+	io.Writer
+	Load   func(href string) ([]byte, error)
+	Codes  symbology.Codes
+	Debug  bool
+	Logger Logger
+	ids    map[string]string
+	buffer bytes.Buffer
+}
+
+// NewDefs creates definitions of a canvas writing rendered objects into "w"
+func NewDefs(w io.Writer) *Defs {
+	return &Defs{Writer: w, ids: map[string]string{}}
+}
+
+// DefsOf returns definitions of the canvas created with svg.New(defs), or nil if there are none
+func DefsOf(canvas *svg.SVG) *Defs {
+	defs, _ := canvas.Writer.(*Defs)
+	return defs
+}
+
+// logf writes a problem of rendering with the logger of the canvas Defs
+func logf(canvas *svg.SVG, format string, v ...interface{}) {
+	if defs := DefsOf(canvas); defs != nil && defs.Logger != nil {
+		defs.Logger.Printf(format, v...)
+		return
+	}
+	log.Printf(format, v...)
+}
+
+// debugOf returns true if failures should be written into the canvas
+func debugOf(canvas *svg.SVG) bool {
+	defs := DefsOf(canvas)
+	return defs != nil && defs.Debug
+}
+
+// codesOf returns codes of the canvas Defs, only SIDC are looked up without them
+func codesOf(canvas *svg.SVG) symbology.Codes {
+	if defs := DefsOf(canvas); defs != nil {
+		return defs.Codes
+	}
+	return nil
+}
+
+// Define returns id of the definition with the key. "draw" writes the definition with the id when the key is used
+// the first time, its error is returned and the key stays undefined.
+// Ids are derived from keys, so equal definitions of different tiles have equal ids.
+func (defs *Defs) Define(key string, draw func(canvas *svg.SVG, id string) error) (string, error) {
+	if id, ok := defs.ids[key]; ok {
+		return id, nil
+	}
+	hash := fnv.New64a()
+	hash.Write([]byte(key))
+	id := fmt.Sprintf("def-%x", hash.Sum64())
+	if err := draw(svg.New(&defs.buffer), id); err != nil {
+		return "", err
+	}
+	defs.ids[key] = id
+	return id, nil
+}
+
+// Len returns count of definitions
+func (defs *Defs) Len() int {
+	return len(defs.ids)
+}
+
+// Render writes the <defs> section into the canvas if there are definitions
+func (defs *Defs) Render(canvas *svg.SVG) {
+	if len(defs.ids) == 0 {
+		return
+	}
+	canvas.Def()
+	canvas.Writer.Write(defs.buffer.Bytes())
+	canvas.DefEnd()
+}
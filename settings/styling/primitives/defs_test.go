@@ -0,0 +1,149 @@
+package primitives
+
+import (
+	"bytes"
+	"errors"
+	"expvar"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/TerraFactory/svgo"
+	"github.com/TerraFactory/tilegenerator/database/entities"
+	"github.com/TerraFactory/wktparser/geometry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefs_Define(t *testing.T) {
+	var body, result bytes.Buffer
+	defs := NewDefs(&body)
+	canvas := svg.New(defs)
+	assert.Equal(t, defs, DefsOf(canvas))
+	assert.Nil(t, DefsOf(svg.New(&result)))
+
+	draws := 0
+	circle := func(target *svg.SVG, id string) error {
+		draws++
+		target.Circle(0, 0, 4, "fill:red", `id="`+id+`"`)
+		return nil
+	}
+	first, err := defs.Define("circle|4", circle)
+	assert.Nil(t, err)
+	second, err := defs.Define("circle|4", circle)
+	assert.Nil(t, err)
+	assert.Equal(t, first, second)
+	assert.Equal(t, 1, draws)
+
+	_, err = defs.Define("image|broken", func(target *svg.SVG, id string) error { return errors.New("broken") })
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, defs.Len())
+
+	defs.Render(svg.New(&result))
+	assert.Equal(t, 1, strings.Count(result.String(), "<circle"))
+	assert.Contains(t, result.String(), `id="`+first+`"`)
+	assert.Equal(t, "", body.String())
+}
+
+func TestImagePrimitive_Link(t *testing.T) {
+	file, err := ioutil.TempFile("", "symbol")
+	assert.Nil(t, err)
+	defer os.Remove(file.Name())
+	file.WriteString(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`)
+	file.Close()
+
+	img, err := NewImagePrimitive(&map[string]interface{}{"Href": file.Name()})
+	assert.Nil(t, err)
+	var body bytes.Buffer
+	link, err := img.link(svg.New(&body), img.Href)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(link, "data:image/svg+xml;base64,"))
+
+	defs := NewDefs(&body)
+	defs.Load = func(href string) ([]byte, error) {
+		return nil, errors.New("Not found " + href)
+	}
+	_, err = img.link(svg.New(defs), img.Href)
+	assert.EqualError(t, err, "Not found "+file.Name())
+}
+
+func TestImagePrimitive_Placeholder(t *testing.T) {
+	img, err := NewImagePrimitive(&map[string]interface{}{"Width": int64(24), "Height": int64(20)})
+	assert.Nil(t, err)
+	img.Scale = 2
+
+	var body, result bytes.Buffer
+	defs := NewDefs(&body)
+	canvas := svg.New(defs)
+	img.renderPlaceholder(canvas, 10, 20, 10, errors.New("HTTP 404"))
+	img.renderPlaceholder(canvas, 30, 40, 10, errors.New("HTTP 404"))
+	defs.Render(svg.New(&result))
+
+	assert.Equal(t, 1, strings.Count(result.String(), "<rect"))
+	assert.Contains(t, result.String(), `width="24" height="20"`)
+	assert.Equal(t, 2, strings.Count(body.String(), "<use"))
+	assert.Contains(t, body.String(), `transform="translate(30,40) scale(2)"`)
+}
+
+func TestImagePrimitive_ExpandHref(t *testing.T) {
+	img, err := NewImagePrimitive(&map[string]interface{}{"Href": "${prop.icon}", "Directory": "/styles"})
+	assert.Nil(t, err)
+	object := &entities.MapObject{Properties: entities.Properties{"icon": "home.svg"}}
+
+	href, err := img.expandHref(img.Href, object, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, "file:///styles/home.svg", href)
+
+	/* Object data can't point to files outside of the styles directory */
+	for _, icon := range []string{"/etc/passwd", "../../config.toml", "file:///etc/passwd"} {
+		object.Properties["icon"] = icon
+		_, err = img.expandHref(img.Href, object, 10)
+		assert.NotNil(t, err, icon)
+		_, err = img.expandHref("${prop.icon|raw}", object, 10)
+		assert.NotNil(t, err, icon)
+	}
+
+	object.Properties["icon"] = "https://example.com/a.png"
+	href, err = img.expandHref("${prop.icon|raw}", object, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://example.com/a.png", href)
+}
+
+// testPoint is a point geometry of test objects
+type testPoint struct {
+	geometry.Geometry
+}
+
+func (p testPoint) AsPoint() (*geometry.Point, error) {
+	return &geometry.Point{Coordinates: geometry.Coord{X: 10, Y: 10}}, nil
+}
+
+type testLogger []string
+
+func (logger *testLogger) Printf(format string, v ...interface{}) {
+	*logger = append(*logger, fmt.Sprintf(format, v...))
+}
+
+func TestImagePrimitive_Failures(t *testing.T) {
+	img, err := NewImagePrimitive(&map[string]interface{}{"Href": "asset://unknown/${id}", "Fallback": "none"})
+	assert.Nil(t, err)
+	logger := testLogger{}
+	defs := NewDefs(&bytes.Buffer{})
+	defs.Logger = &logger
+	defs.Load = func(href string) ([]byte, error) {
+		return nil, errors.New("Not found")
+	}
+
+	canvas := svg.New(defs)
+	img.Render(canvas, &entities.MapObject{ID: 1, Geometry: testPoint{}}, 10)
+	img.Render(canvas, &entities.MapObject{ID: 2, Geometry: testPoint{}}, 10)
+
+	/* Failures are counted by the href of the style, not by hrefs of each object */
+	assert.Equal(t, int64(2), imageFailures.Get(img.Href).(*expvar.Int).Value())
+	assert.Nil(t, imageFailures.Get("asset://unknown/1"))
+	assert.Equal(t, testLogger{
+		"Can't render IMAGE asset://unknown/1 for object 1: Not found \n",
+		"Can't render IMAGE asset://unknown/2 for object 2: Not found \n",
+	}, logger)
+}
@@ -1,71 +1,199 @@
 package primitives
 
 import (
+	"expvar"
 	"fmt"
+	"html"
 	"math"
-	"strconv"
 	"strings"
 
 	"encoding/base64"
 
 	"github.com/TerraFactory/svgo"
+	"github.com/TerraFactory/tilegenerator/assets"
 	"github.com/TerraFactory/tilegenerator/database/entities"
-	"github.com/TerraFactory/tilegenerator/utils"
 )
 
+// ImagePrimitive draws an image from Href: a URL, a file path relative to the styles directory, "file://..."
+// or "asset://store/name" of a configured asset store. Href is a template, see ExpandTemplate. Format is a MIME type, it is detected if not set.
+// If the image can't be loaded, the Fallback image is drawn instead, or a placeholder of the same size
+// if there is no Fallback. Fallback "none" draws nothing.
+// Hrefs built from object data are checked by assets.ResolveExpanded, relative ones are files of Directory,
+// which is set to the styles directory by the styles loader.
 type ImagePrimitive struct {
-	Width  int64
-	Height int64
-	Scale  float64
-	Href   string
-	Rotate float64
-	Format string
-	bytes  []byte
+	Width     ZoomValue
+	Height    ZoomValue
+	Scale     float64
+	Href      string
+	Rotate    float64
+	Format    string
+	Fallback  string
+	Directory string
 }
 
-func (img ImagePrimitive) Render(svg *svg.SVG, object *entities.MapObject) {
-	point, _ := object.Geometry.AsPoint()
-	resultHref := strings.Replace(img.Href, "${ID}", strconv.Itoa(object.ID), 1)
+// Counts of failed images by href templates of styles, they are published at /debug/vars
+var imageFailures = expvar.NewMap("image_failures")
 
+func (img ImagePrimitive) Render(canvas *svg.SVG, object *entities.MapObject, zoom int) {
+	point, err := object.Geometry.AsPoint()
+	if err != nil {
+		logf(canvas, "Can't render IMAGE for object %v: %v \n", object.ID, err)
+		return
+	}
 	img.Rotate = object.Azimut
 	img.Scale = object.Scale
-	img.Width, img.Height = img.Width*int64(img.Scale), img.Height*int64(img.Scale)
-
-	if result, err := utils.GetImgByURL(resultHref); err == nil {
-		img.bytes = result
-		inlineBase64Img := base64.StdEncoding.EncodeToString(img.bytes)
-		svg.TranslateRotate(
-			int(math.Floor(point.Coordinates.X+.5)),
-			int(math.Floor(point.Coordinates.Y+0.5)),
-			img.Rotate)
-		svg.Image(-int(img.Width)/2, -int(img.Height)/2, int(img.Width), int(img.Height), "data:"+img.Format+";base64,"+inlineBase64Img)
-		svg.Gend()
-	} else {
-		fmt.Printf("Can't render %s because of err: '%s'", resultHref, err.Error())
+	x, y := int(math.Floor(point.Coordinates.X+.5)), int(math.Floor(point.Coordinates.Y+0.5))
+
+	resultHref, err := img.expandHref(img.Href, object, zoom)
+	if err == nil {
+		err = img.renderImage(canvas, resultHref, x, y, zoom)
+	}
+	if err == nil {
+		return
+	}
+	imageFailures.Add(img.Href, 1)
+	logf(canvas, "Can't render IMAGE %s for object %v: %v \n", resultHref, object.ID, err)
+	if debugOf(canvas) {
+		fmt.Fprintf(canvas.Writer, "<!-- %s -->\n", strings.Replace(
+			fmt.Sprintf("Can't render IMAGE %s for object %v: %v", resultHref, object.ID, err), "--", "- -", -1))
+	}
+
+	switch img.Fallback {
+	case "none":
+	case "":
+		img.renderPlaceholder(canvas, x, y, zoom, err)
+	default:
+		fallback, fallbackErr := img.expandHref(img.Fallback, object, zoom)
+		if fallbackErr == nil {
+			fallbackErr = img.renderImage(canvas, fallback, x, y, zoom)
+		}
+		if fallbackErr != nil {
+			imageFailures.Add(img.Fallback, 1)
+			logf(canvas, "Can't render fallback IMAGE %s for object %v: %v \n", fallback, object.ID, fallbackErr)
+			img.renderPlaceholder(canvas, x, y, zoom, err)
+		}
+	}
+}
+
+// expandHref returns the href template expanded with the object, hrefs built from object data are checked
+func (img ImagePrimitive) expandHref(href string, object *entities.MapObject, zoom int) (string, error) {
+	if !strings.Contains(href, "${") {
+		return href, nil
+	}
+	return assets.ResolveExpanded(ExpandTemplate(href, object, zoom, EscapeURL), img.Directory)
+}
+
+// renderImage draws the image by href centered at (x, y)
+func (img ImagePrimitive) renderImage(canvas *svg.SVG, href string, x, y, zoom int) error {
+	if defs := DefsOf(canvas); defs != nil {
+		// The image is defined in its size on the zoom, objects scale it
+		width, height := int(img.Width.At(zoom)), int(img.Height.At(zoom))
+		key := fmt.Sprintf("image|%s|%v|%v|%s", img.Format, width, height, href)
+		id, err := defs.Define(key, func(target *svg.SVG, id string) error {
+			link, err := img.link(canvas, href)
+			if err == nil {
+				target.Image(-width/2, -height/2, width, height, link, fmt.Sprintf(`id="%s"`, id))
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		canvas.Use(0, 0, "#"+id, fmt.Sprintf(`transform="translate(%v,%v) rotate(%v) scale(%v)"`, x, y, img.Rotate, img.Scale))
+		return nil
+	}
+
+	link, err := img.link(canvas, href)
+	if err != nil {
+		return err
 	}
+	width, height := img.Width.At(zoom)*img.Scale, img.Height.At(zoom)*img.Scale
+	canvas.TranslateRotate(x, y, img.Rotate)
+	canvas.Image(-int(width)/2, -int(height)/2, int(width), int(height), link)
+	canvas.Gend()
+	return nil
+}
+
+// renderPlaceholder draws a frame with a question mark of the image size centered at (x, y)
+func (img ImagePrimitive) renderPlaceholder(canvas *svg.SVG, x, y, zoom int, reason error) {
+	width, height := img.Width.At(zoom), img.Height.At(zoom)
+	attributes := []string{fmt.Sprintf(`transform="translate(%v,%v) scale(%v)"`, x, y, img.Scale)}
+	if debugOf(canvas) {
+		attributes = append(attributes, fmt.Sprintf(`data-error="%s"`, html.EscapeString(reason.Error())))
+	}
+	shape := func(target *svg.SVG) {
+		target.Rect(-int(width)/2, -int(height)/2, int(width), int(height),
+			"fill:white; fill-opacity:0.7; stroke:#d32f2f; stroke-width:1; stroke-dasharray:2,2")
+		size := math.Min(width, height) * 0.7
+		target.Text(0, int(size*0.35), "?",
+			fmt.Sprintf("font-family:sans-serif; font-size:%vpx; font-weight:bold; fill:#d32f2f; text-anchor:middle", size))
+	}
+
+	if defs := DefsOf(canvas); defs != nil {
+		id, _ := defs.Define(fmt.Sprintf("placeholder|%v|%v", int(width), int(height)), func(target *svg.SVG, id string) error {
+			target.Gid(id)
+			shape(target)
+			target.Gend()
+			return nil
+		})
+		canvas.Use(0, 0, "#"+id, attributes...)
+		return
+	}
+	canvas.Group(attributes...)
+	shape(canvas)
+	canvas.Gend()
+}
+
+// link returns the image by href as a data URL. It is loaded by the Defs of the canvas if they have a loader.
+func (img ImagePrimitive) link(canvas *svg.SVG, href string) (string, error) {
+	load := assets.Load
+	if defs := DefsOf(canvas); defs != nil && defs.Load != nil {
+		load = defs.Load
+	}
+	data, err := load(href)
+	if err != nil {
+		return "", err
+	}
+	format := img.Format
+	if format == "" {
+		format = assets.ContentType(data)
+	}
+	return "data:" + format + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// Size returns size of the image for the object, labels are placed around it
+func (img ImagePrimitive) Size(canvas *svg.SVG, object *entities.MapObject, zoom int) (float64, float64) {
+	return img.Width.At(zoom) * object.Scale, img.Height.At(zoom) * object.Scale
 }
 
 func NewImagePrimitive(params *map[string]interface{}) (ImagePrimitive, error) {
 	img := ImagePrimitive{}
 	for key, value := range *params {
+		var err error
 		switch strings.ToUpper(key) { // Switch here is temporary workaround. I should use reflect instead.
 		case "WIDTH":
-			img.Width = value.(int64)
+			img.Width, err = paramZoomValue(key, value)
 		case "HEIGHT":
-			img.Height = value.(int64)
+			img.Height, err = paramZoomValue(key, value)
 		case "HREF":
-			img.Href = value.(string)
+			if img.Href, err = paramString(key, value); err == nil {
+				err = paramTemplate(key, img.Href)
+			}
 		case "ROTATE":
-			img.Rotate = value.(float64)
+			img.Rotate, err = paramFloat(key, value)
 		case "FORMAT":
-			img.Format = value.(string)
+			img.Format, err = paramString(key, value)
+		case "FALLBACK":
+			if img.Fallback, err = paramString(key, value); err == nil {
+				err = paramTemplate(key, img.Fallback)
+			}
+		case "DIRECTORY":
+			img.Directory, err = paramString(key, value)
+		}
+		if err != nil {
+			return img, err
 		}
 	}
 
 	return img, nil
 }
-
-func floatToString(inputNum float64) string {
-	// to convert a float number to a string
-	return strconv.FormatFloat(inputNum, 'f', 6, 64)
-}
@@ -0,0 +1,31 @@
+package primitives
+
+import (
+	"github.com/TerraFactory/svgo"
+	"github.com/TerraFactory/tilegenerator/database/entities"
+)
+
+// LinePrimitive draws a line geometry as a polyline
+type LinePrimitive struct {
+	Paint
+}
+
+func (line LinePrimitive) Render(svg *svg.SVG, object *entities.MapObject, zoom int) {
+	lineString, err := object.Geometry.AsLineString()
+	if err != nil {
+		logf(svg, "Can't render LINE for object %v: %v \n", object.ID, err)
+		return
+	}
+	xs, ys := coordsToXsYs(lineString.Coordinates)
+	svg.Polyline(xs, ys, line.forObject(object).style("", false, zoom))
+}
+
+func NewLinePrimitive(params *map[string]interface{}) (LinePrimitive, error) {
+	line := LinePrimitive{Paint: newPaint()}
+	for key, value := range *params {
+		if _, err := line.setParam(key, value); err != nil {
+			return line, err
+		}
+	}
+	return line, nil
+}
@@ -0,0 +1,240 @@
+package primitives
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+)
+
+// FontMetrics are widths of characters and vertical metrics of a font in font sizes (ems)
+type FontMetrics struct {
+	Ascent       float64
+	Descent      float64
+	widths       map[rune]float64
+	defaultWidth float64
+}
+
+// Width returns width of the text in pixels for the font size
+func (metrics *FontMetrics) Width(text string, size, letterSpacing float64) float64 {
+	width := 0.0
+	count := 0
+	for _, r := range text {
+		w, ok := metrics.widths[r]
+		if !ok {
+			w = metrics.defaultWidth
+		}
+		width += w * size
+		count++
+	}
+	if count > 1 {
+		width += float64(count-1) * letterSpacing
+	}
+	return width
+}
+
+// Widths of printable ASCII characters from " " to "~" of Helvetica (and metric-compatible Arial, Liberation Sans)
+// in 1/1000 of em. Other characters have width of a digit.
+var helveticaWidths = []float64{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = []float64{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
+
+func newEmbeddedMetrics(widths []float64) *FontMetrics {
+	metrics := FontMetrics{Ascent: 0.718, Descent: 0.207, widths: map[rune]float64{}, defaultWidth: 0.556}
+	for i, w := range widths {
+		metrics.widths[rune(' '+i)] = w / 1000
+	}
+	return &metrics
+}
+
+var helvetica = newEmbeddedMetrics(helveticaWidths)
+var helveticaBold = newEmbeddedMetrics(helveticaBoldWidths)
+
+// defaultFontMetrics returns embedded metrics of a sans-serif font of the weight
+func defaultFontMetrics(weight int64) *FontMetrics {
+	if weight >= 600 {
+		return helveticaBold
+	}
+	return helvetica
+}
+
+var loadedFonts = map[string]*FontMetrics{}
+var loadedFontsMutex sync.Mutex
+
+// ClearFontMetrics forgets loaded fonts, so edited font files are read again
+func ClearFontMetrics() {
+	loadedFontsMutex.Lock()
+	defer loadedFontsMutex.Unlock()
+	loadedFonts = map[string]*FontMetrics{}
+}
+
+// LoadFontMetrics reads metrics of a TrueType or OpenType font file. Fonts are read once.
+func LoadFontMetrics(path string) (*FontMetrics, error) {
+	loadedFontsMutex.Lock()
+	defer loadedFontsMutex.Unlock()
+
+	if metrics, ok := loadedFonts[path]; ok {
+		return metrics, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := parseFontMetrics(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	loadedFonts[path] = metrics
+	return metrics, nil
+}
+
+var errFontFormat = errors.New("Unsupported or broken font file")
+
+// fontReader reads big-endian numbers from font tables. Reading out of the data sets err instead of panicking.
+type fontReader struct {
+	data []byte
+	err  error
+}
+
+func (r *fontReader) u16(offset int) int {
+	if offset < 0 || offset+2 > len(r.data) {
+		r.err = errFontFormat
+		return 0
+	}
+	return int(binary.BigEndian.Uint16(r.data[offset:]))
+}
+
+func (r *fontReader) i16(offset int) int {
+	return int(int16(r.u16(offset)))
+}
+
+func (r *fontReader) u32(offset int) int {
+	if offset < 0 || offset+4 > len(r.data) {
+		r.err = errFontFormat
+		return 0
+	}
+	return int(binary.BigEndian.Uint32(r.data[offset:]))
+}
+
+// parseFontMetrics reads "head", "hhea", "hmtx" and "cmap" tables of a font
+func parseFontMetrics(data []byte) (*FontMetrics, error) {
+	r := fontReader{data: data}
+	tables := map[string]int{}
+	numTables := r.u16(4)
+	for i := 0; i < numTables && r.err == nil; i++ {
+		record := 12 + i*16
+		if record+16 > len(data) {
+			return nil, errFontFormat
+		}
+		tables[string(data[record:record+4])] = r.u32(record + 8)
+	}
+	for _, tag := range []string{"head", "hhea", "hmtx", "cmap"} {
+		if _, ok := tables[tag]; !ok {
+			return nil, fmt.Errorf("Font has no %s table", tag)
+		}
+	}
+
+	unitsPerEm := float64(r.u16(tables["head"] + 18))
+	hhea := tables["hhea"]
+	ascender, descender := r.i16(hhea+4), r.i16(hhea+6)
+	numberOfHMetrics := r.u16(hhea + 34)
+	if r.err != nil || unitsPerEm == 0 || numberOfHMetrics == 0 {
+		return nil, errFontFormat
+	}
+
+	advance := func(glyph int) float64 {
+		if glyph >= numberOfHMetrics {
+			glyph = numberOfHMetrics - 1
+		}
+		return float64(r.u16(tables["hmtx"]+glyph*4)) / unitsPerEm
+	}
+
+	glyphs, err := readCmap(&r, tables["cmap"])
+	if err != nil {
+		return nil, err
+	}
+	metrics := FontMetrics{
+		Ascent:       float64(ascender) / unitsPerEm,
+		Descent:      -float64(descender) / unitsPerEm,
+		widths:       map[rune]float64{},
+		defaultWidth: advance(0),
+	}
+	for char, glyph := range glyphs {
+		metrics.widths[char] = advance(glyph)
+	}
+	if w, ok := metrics.widths['0']; ok {
+		metrics.defaultWidth = w
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &metrics, nil
+}
+
+// readCmap returns glyph indices of characters from a Unicode subtable of format 4 or 12
+func readCmap(r *fontReader, cmap int) (map[rune]int, error) {
+	subtable := -1
+	for i := 0; i < r.u16(cmap+2) && r.err == nil; i++ {
+		record := cmap + 4 + i*8
+		platform, encoding := r.u16(record), r.u16(record+2)
+		offset := cmap + r.u32(record+4)
+		format := r.u16(offset)
+		unicode := platform == 0 || (platform == 3 && (encoding == 1 || encoding == 10))
+		if unicode && (format == 4 || format == 12) && (subtable < 0 || format == 12) {
+			subtable = offset
+		}
+	}
+	if r.err != nil || subtable < 0 {
+		return nil, errors.New("Font has no Unicode character map")
+	}
+
+	glyphs := map[rune]int{}
+	if r.u16(subtable) == 12 {
+		numGroups := r.u32(subtable + 12)
+		for i := 0; i < numGroups && r.err == nil; i++ {
+			group := subtable + 16 + i*12
+			start, end, glyph := r.u32(group), r.u32(group+4), r.u32(group+8)
+			for char := start; char <= end && char-start < 0x10000; char++ {
+				glyphs[rune(char)] = glyph + char - start
+			}
+		}
+		return glyphs, r.err
+	}
+
+	segCount := r.u16(subtable+6) / 2
+	endCodes := subtable + 14
+	startCodes := endCodes + segCount*2 + 2
+	idDeltas := startCodes + segCount*2
+	idRangeOffsets := idDeltas + segCount*2
+	for i := 0; i < segCount && r.err == nil; i++ {
+		start, end := r.u16(startCodes+i*2), r.u16(endCodes+i*2)
+		delta, rangeOffset := r.u16(idDeltas+i*2), r.u16(idRangeOffsets+i*2)
+		for char := start; char <= end && char != 0xFFFF; char++ {
+			glyph := 0
+			if rangeOffset == 0 {
+				glyph = (char + delta) & 0xFFFF
+			} else if glyph = r.u16(idRangeOffsets + i*2 + rangeOffset + (char-start)*2); glyph != 0 {
+				glyph = (glyph + delta) & 0xFFFF
+			}
+			if glyph != 0 {
+				glyphs[rune(char)] = glyph
+			}
+		}
+	}
+	return glyphs, r.err
+}
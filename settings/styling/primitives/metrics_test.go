@@ -0,0 +1,88 @@
+package primitives
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testFont builds a font with "head", "hhea", "hmtx" and "cmap" tables. Glyph 1 is "A", glyph 2 is "B".
+func testFont() []byte {
+	u16 := func(values ...int) []byte {
+		result := []byte{}
+		for _, v := range values {
+			result = append(result, byte(v>>8), byte(v))
+		}
+		return result
+	}
+
+	head := make([]byte, 54)
+	binary.BigEndian.PutUint16(head[18:], 1000)
+	hhea := make([]byte, 36)
+	binary.BigEndian.PutUint16(hhea[4:], 800)
+	binary.BigEndian.PutUint16(hhea[6:], uint16(0x10000-200))
+	binary.BigEndian.PutUint16(hhea[34:], 3)
+	hmtx := u16(500, 0, 600, 0, 700, 0)
+	// Format 4 subtable with segments "A".."B" and the final 0xFFFF
+	subtable := u16(4, 32, 0, 4, 4, 1, 0, 'B', 0xFFFF, 0, 'A', 0xFFFF, 0x10000-'A'+1, 1, 0, 0)
+	cmap := append(u16(0, 1, 3, 1, 0, 12), subtable...)
+
+	tables := []struct {
+		tag  string
+		data []byte
+	}{{"cmap", cmap}, {"head", head}, {"hhea", hhea}, {"hmtx", hmtx}}
+	font := u16(1, 0, len(tables), 0, 0, 0)
+	offset := 12 + len(tables)*16
+	for _, table := range tables {
+		record := make([]byte, 16)
+		copy(record, table.tag)
+		binary.BigEndian.PutUint32(record[8:], uint32(offset))
+		binary.BigEndian.PutUint32(record[12:], uint32(len(table.data)))
+		font = append(font, record...)
+		offset += len(table.data)
+	}
+	for _, table := range tables {
+		font = append(font, table.data...)
+	}
+	return font
+}
+
+func TestParseFontMetrics(t *testing.T) {
+	metrics, err := parseFontMetrics(testFont())
+	assert.Nil(t, err)
+	assert.Equal(t, 0.8, metrics.Ascent)
+	assert.Equal(t, 0.2, metrics.Descent)
+	assert.Equal(t, 13.0, metrics.Width("AB", 10, 0))
+	assert.Equal(t, 18.0, metrics.Width("AxB", 10, 0), "unknown characters have width of the missing glyph")
+
+	_, err = parseFontMetrics(testFont()[:40])
+	assert.NotNil(t, err)
+}
+
+func TestFontMetrics_Width(t *testing.T) {
+	assert.InDelta(t, 26.67, helvetica.Width("Home", 10, 0), 0.001)
+	assert.InDelta(t, 29.28, helveticaBold.Width("Home", 10, 0.5), 0.001)
+}
+
+func TestClearFontMetrics(t *testing.T) {
+	file, err := ioutil.TempFile("", "font")
+	assert.Nil(t, err)
+	defer os.Remove(file.Name())
+	file.Write(testFont())
+	file.Close()
+
+	first, err := LoadFontMetrics(file.Name())
+	assert.Nil(t, err)
+	ioutil.WriteFile(file.Name(), []byte("broken"), 0644)
+	cached, err := LoadFontMetrics(file.Name())
+	assert.Nil(t, err)
+	assert.Equal(t, first, cached)
+
+	/* An edited font is read again after the cache is cleared */
+	ClearFontMetrics()
+	_, err = LoadFontMetrics(file.Name())
+	assert.NotNil(t, err)
+}
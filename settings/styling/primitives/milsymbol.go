@@ -0,0 +1,125 @@
+package primitives
+
+import (
+	"expvar"
+	"fmt"
+	"strings"
+
+	"github.com/TerraFactory/svgo"
+	"github.com/TerraFactory/tilegenerator/database/entities"
+	"github.com/TerraFactory/tilegenerator/symbology"
+)
+
+// MilitarySymbolPrimitive draws a military symbol generated from Code, a template of a SIDC or an internal code
+// mapped to a SIDC by Codes of the canvas Defs. FrameSize ("Size" parameter) is the height of a friendly ground unit frame, it is multiplied
+// by the object scale. Amplifiers are templates written to the right of the frame.
+type MilitarySymbolPrimitive struct {
+	Code              string
+	FrameSize         ZoomValue
+	UniqueDesignation string
+	HigherFormation   string
+	AdditionalInfo    string
+	FontFamily        string
+}
+
+// Counts of object codes which are not SIDC and are not mapped to them, they are published at /debug/vars
+var unknownSymbolCodes = expvar.NewMap("unknown_symbol_codes")
+
+// symbol returns the parsed symbol of the object and its size on the zoom, codes are mapped by Defs of the canvas
+func (symbol MilitarySymbolPrimitive) symbol(canvas *svg.SVG, object *entities.MapObject, zoom int) (*symbology.Symbol, float64, error) {
+	code := ExpandTemplate(symbol.Code, object, zoom, EscapeText)
+	sidc, ok := codesOf(canvas).Lookup(code)
+	if !ok {
+		unknownSymbolCodes.Add(code, 1)
+		return nil, 0, fmt.Errorf("Code %s is not a SIDC and is not mapped to it", code)
+	}
+	parsed, err := symbology.Parse(sidc)
+	if err != nil {
+		return nil, 0, err
+	}
+	size := symbol.FrameSize.At(zoom)
+	if object.Scale > 0 {
+		size *= object.Scale
+	}
+	return parsed, size, nil
+}
+
+func (symbol MilitarySymbolPrimitive) Render(canvas *svg.SVG, object *entities.MapObject, zoom int) {
+	point, err := object.Geometry.AsPoint()
+	if err != nil {
+		logf(canvas, "Can't render SYMBOL for object %v: %v \n", object.ID, err)
+		return
+	}
+	parsed, size, err := symbol.symbol(canvas, object, zoom)
+	if err != nil {
+		logf(canvas, "Can't render SYMBOL for object %v: %v \n", object.ID, err)
+		return
+	}
+
+	x, y := point.Coordinates.X, point.Coordinates.Y
+	RenderSymbol(canvas, parsed, x, y, size, 0)
+	parsed.RenderAmplifiers(canvas, x, y, size, symbology.Amplifiers{
+		UniqueDesignation: ExpandTemplate(symbol.UniqueDesignation, object, zoom, EscapeText),
+		HigherFormation:   ExpandTemplate(symbol.HigherFormation, object, zoom, EscapeText),
+		AdditionalInfo:    ExpandTemplate(symbol.AdditionalInfo, object, zoom, EscapeText),
+	}, symbol.FontFamily)
+}
+
+// RenderSymbol draws the symbol centered at (x, y) and rotated by "rotate" degrees.
+// Symbols are shared by objects if the canvas has Defs.
+func RenderSymbol(canvas *svg.SVG, symbol *symbology.Symbol, x, y, size, rotate float64) {
+	transform := fmt.Sprintf(`transform="translate(%v,%v) rotate(%v)"`, x, y, rotate)
+	if defs := DefsOf(canvas); defs != nil {
+		id, _ := defs.Define(fmt.Sprintf("symbol|%s|%v", symbol.SIDC, size), func(target *svg.SVG, id string) error {
+			target.Gid(id)
+			symbol.Render(target, size)
+			target.Gend()
+			return nil
+		})
+		canvas.Use(0, 0, "#"+id, transform)
+		return
+	}
+	canvas.Group(transform)
+	symbol.Render(canvas, size)
+	canvas.Gend()
+}
+
+// Size returns size of the symbol frame for the object, labels are placed around it
+func (symbol MilitarySymbolPrimitive) Size(canvas *svg.SVG, object *entities.MapObject, zoom int) (float64, float64) {
+	parsed, size, err := symbol.symbol(canvas, object, zoom)
+	if err != nil {
+		return 0, 0
+	}
+	return parsed.Size(size)
+}
+
+func NewMilitarySymbolPrimitive(params *map[string]interface{}) (MilitarySymbolPrimitive, error) {
+	symbol := MilitarySymbolPrimitive{Code: "${code}", FrameSize: Constant(30), UniqueDesignation: "${label}", FontFamily: "sans-serif"}
+	for key, value := range *params {
+		var err error
+		var template *string
+		switch strings.ToUpper(key) {
+		case "CODE":
+			template = &symbol.Code
+		case "UNIQUEDESIGNATION":
+			template = &symbol.UniqueDesignation
+		case "HIGHERFORMATION":
+			template = &symbol.HigherFormation
+		case "ADDITIONALINFO":
+			template = &symbol.AdditionalInfo
+		case "SIZE":
+			symbol.FrameSize, err = paramZoomValue(key, value)
+		case "FONTFAMILY", "FONT":
+			symbol.FontFamily, err = paramString(key, value)
+		}
+		if template != nil {
+			if *template, err = paramString(key, value); err == nil {
+				err = paramTemplate(key, *template)
+			}
+		}
+		if err != nil {
+			return symbol, err
+		}
+	}
+	return symbol, nil
+}
@@ -0,0 +1,150 @@
+package primitives
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/TerraFactory/svgo"
+	"github.com/TerraFactory/tilegenerator/database/entities"
+)
+
+// Paint contains stroke and fill parameters of shape primitives (LINE, POLYGON, CIRCLE, PATH).
+// Object colors override them: ColorOuter replaces the stroke color, ColorInner replaces the fill color of filled shapes.
+// Pattern fills the shape with "hatch", "crosshatch" or "dots" of the fill color instead of a solid color.
+type Paint struct {
+	StrokeColor   string
+	StrokeWidth   ZoomValue
+	StrokeOpacity float64
+	DashArray     []float64
+	LineCap       string
+	LineJoin      string
+	FillColor     string
+	FillOpacity   float64
+	Pattern       string
+	PatternSize   float64
+}
+
+func newPaint() Paint {
+	return Paint{
+		StrokeColor:   "black",
+		StrokeWidth:   Constant(1),
+		StrokeOpacity: 1,
+		FillColor:     "none",
+		FillOpacity:   1,
+		PatternSize:   8,
+	}
+}
+
+// setParam sets a parameter of the paint. It returns false if the key is not a paint parameter.
+func (paint *Paint) setParam(key string, value interface{}) (bool, error) {
+	var err error
+	switch strings.ToUpper(key) {
+	case "STROKE", "STROKECOLOR":
+		paint.StrokeColor, err = paramString(key, value)
+	case "STROKEWIDTH":
+		paint.StrokeWidth, err = paramZoomValue(key, value)
+	case "STROKEOPACITY":
+		paint.StrokeOpacity, err = paramFloat(key, value)
+	case "DASHARRAY":
+		paint.DashArray, err = paramFloats(key, value)
+	case "LINECAP":
+		paint.LineCap, err = paramString(key, value)
+	case "LINEJOIN":
+		paint.LineJoin, err = paramString(key, value)
+	case "FILL", "FILLCOLOR":
+		paint.FillColor, err = paramString(key, value)
+	case "FILLOPACITY":
+		paint.FillOpacity, err = paramFloat(key, value)
+	case "PATTERN":
+		paint.Pattern, err = paramString(key, value)
+		if err == nil && paint.Pattern != "hatch" && paint.Pattern != "crosshatch" && paint.Pattern != "dots" {
+			err = paramErrorf(key, "Unknown pattern %s", paint.Pattern)
+		}
+	case "PATTERNSIZE":
+		paint.PatternSize, err = paramFloat(key, value)
+	default:
+		return false, nil
+	}
+	return true, err
+}
+
+// forObject returns the paint with colors overridden by the object colors
+func (paint Paint) forObject(object *entities.MapObject) Paint {
+	if object.ColorOuter != "" {
+		paint.StrokeColor = object.ColorOuter
+	}
+	if object.ColorInner != "" && (paint.FillColor != "none" || paint.Pattern != "") {
+		paint.FillColor = object.ColorInner
+	}
+	return paint
+}
+
+func (paint Paint) patternID(object *entities.MapObject) string {
+	hash := fnv.New32a()
+	fmt.Fprintf(hash, "%v", paint)
+	return fmt.Sprintf("pattern%v-%x", object.ID, hash.Sum32())
+}
+
+// renderPattern writes definition of the pattern and returns its id, or an empty string if the paint has no pattern.
+// Patterns are shared by objects if the canvas has Defs.
+func (paint Paint) renderPattern(canvas *svg.SVG, object *entities.MapObject, zoom int) string {
+	if paint.Pattern == "" {
+		return ""
+	}
+	if defs := DefsOf(canvas); defs != nil {
+		id, _ := defs.Define(fmt.Sprintf("pattern|%v|%v", paint, zoom), func(target *svg.SVG, id string) error {
+			paint.writePattern(target, id, zoom)
+			return nil
+		})
+		return id
+	}
+	id := paint.patternID(object)
+	canvas.Def()
+	paint.writePattern(canvas, id, zoom)
+	canvas.DefEnd()
+	return id
+}
+
+func (paint Paint) writePattern(svg *svg.SVG, id string, zoom int) {
+	size := int(paint.PatternSize)
+	lineStyle := fmt.Sprintf("stroke:%v; stroke-width:%v; stroke-opacity:%v", paint.FillColor, paint.StrokeWidth.At(zoom), paint.FillOpacity)
+
+	switch paint.Pattern {
+	case "hatch":
+		svg.Pattern(id, 0, 0, size, size, "user", `patternTransform="rotate(45)"`)
+		svg.Line(0, 0, 0, size, lineStyle)
+	case "crosshatch":
+		svg.Pattern(id, 0, 0, size, size, "user", `patternTransform="rotate(45)"`)
+		svg.Line(0, 0, 0, size, lineStyle)
+		svg.Line(0, 0, size, 0, lineStyle)
+	case "dots":
+		svg.Pattern(id, 0, 0, size, size, "user")
+		svg.Circle(size/2, size/2, size/4+1, fmt.Sprintf("fill:%v; fill-opacity:%v", paint.FillColor, paint.FillOpacity))
+	}
+	svg.PatternEnd()
+}
+
+// style returns CSS style of the shape. "patternID" replaces the fill color if it is not empty.
+func (paint Paint) style(patternID string, filled bool, zoom int) string {
+	style := fmt.Sprintf("stroke:%v; stroke-width:%v; stroke-opacity:%v;", paint.StrokeColor, paint.StrokeWidth.At(zoom), paint.StrokeOpacity)
+	if len(paint.DashArray) > 0 {
+		style += fmt.Sprintf(" stroke-dasharray:%v;", floatsToString(paint.DashArray))
+	}
+	if paint.LineCap != "" {
+		style += fmt.Sprintf(" stroke-linecap:%v;", paint.LineCap)
+	}
+	if paint.LineJoin != "" {
+		style += fmt.Sprintf(" stroke-linejoin:%v;", paint.LineJoin)
+	}
+
+	switch {
+	case !filled:
+		style += " fill:none;"
+	case patternID != "":
+		style += fmt.Sprintf(" fill:url(#%v);", patternID)
+	default:
+		style += fmt.Sprintf(" fill:%v; fill-opacity:%v;", paint.FillColor, paint.FillOpacity)
+	}
+	return style
+}
@@ -0,0 +1,39 @@
+package primitives
+
+import (
+	"testing"
+
+	"github.com/TerraFactory/tilegenerator/database/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPolygonPrimitive(t *testing.T) {
+	polygon, err := NewPolygonPrimitive(&map[string]interface{}{
+		"StrokeColor": "red",
+		"StrokeWidth": int64(2),
+		"DashArray":   []interface{}{int64(8), 4.5},
+		"FillColor":   "blue",
+		"FillOpacity": 0.5,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "stroke:red; stroke-width:2; stroke-opacity:1; stroke-dasharray:8,4.5; fill:blue; fill-opacity:0.5;",
+		polygon.style("", true, 10))
+
+	_, err = NewPolygonPrimitive(&map[string]interface{}{"StrokeWidth": "wide"})
+	assert.NotNil(t, err, "wrong value type should be reported")
+
+	_, err = NewPolygonPrimitive(&map[string]interface{}{"Pattern": "zigzag"})
+	assert.NotNil(t, err, "unknown pattern should be reported")
+}
+
+func TestPaint_forObject(t *testing.T) {
+	paint := newPaint()
+	object := entities.MapObject{ColorOuter: "green", ColorInner: "yellow"}
+
+	overridden := paint.forObject(&object)
+	assert.Equal(t, "green", overridden.StrokeColor)
+	assert.Equal(t, "none", overridden.FillColor, "shapes without fill should stay unfilled")
+
+	paint.FillColor = "white"
+	assert.Equal(t, "yellow", paint.forObject(&object).FillColor)
+}
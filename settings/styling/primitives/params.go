@@ -0,0 +1,106 @@
+package primitives
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/TerraFactory/wktparser/geometry"
+)
+
+// ParamError is an error in the value of a primitive parameter "Key"
+type ParamError struct {
+	Key string
+	Err error
+}
+
+func (e *ParamError) Error() string {
+	return e.Err.Error()
+}
+
+func paramErrorf(key string, format string, args ...interface{}) error {
+	return &ParamError{Key: key, Err: fmt.Errorf(format, args...)}
+}
+
+// paramFloat converts a TOML number into float64. TOML integers are decoded as int64.
+func paramFloat(key string, value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	}
+	return 0, paramErrorf(key, "%s should be a number, got %v", key, value)
+}
+
+func paramInt(key string, value interface{}) (int64, error) {
+	if v, ok := value.(int64); ok {
+		return v, nil
+	}
+	return 0, paramErrorf(key, "%s should be an integer, got %v", key, value)
+}
+
+func paramString(key string, value interface{}) (string, error) {
+	if v, ok := value.(string); ok {
+		return v, nil
+	}
+	return "", paramErrorf(key, "%s should be a string, got %v", key, value)
+}
+
+// paramTemplate checks variables and filters of the template
+func paramTemplate(key string, template string) error {
+	if err := CheckTemplate(template); err != nil {
+		return paramErrorf(key, "%v", err)
+	}
+	return nil
+}
+
+func paramBool(key string, value interface{}) (bool, error) {
+	if v, ok := value.(bool); ok {
+		return v, nil
+	}
+	return false, paramErrorf(key, "%s should be a boolean, got %v", key, value)
+}
+
+// paramFloats reads an array of numbers, e.g. [5, 2.5], or a comma separated string "5, 2.5"
+func paramFloats(key string, value interface{}) ([]float64, error) {
+	result := []float64{}
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			f, err := paramFloat(key, item)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, f)
+		}
+	case string:
+		for _, item := range strings.Split(v, ",") {
+			f, err := strconv.ParseFloat(strings.TrimSpace(item), 64)
+			if err != nil {
+				return nil, paramErrorf(key, "%s should contain numbers, got %v", key, value)
+			}
+			result = append(result, f)
+		}
+	default:
+		return nil, paramErrorf(key, "%s should be an array of numbers, got %v", key, value)
+	}
+	return result, nil
+}
+
+func floatsToString(values []float64) string {
+	strs := make([]string, len(values))
+	for i, v := range values {
+		strs[i] = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return strings.Join(strs, ",")
+}
+
+func coordsToXsYs(coords []geometry.Coord) ([]int, []int) {
+	xs := make([]int, len(coords))
+	ys := make([]int, len(coords))
+	for i, coord := range coords {
+		xs[i], ys[i] = int(coord.X), int(coord.Y)
+	}
+	return xs, ys
+}
@@ -0,0 +1,55 @@
+package primitives
+
+import (
+	"math"
+	"strings"
+
+	"github.com/TerraFactory/svgo"
+	"github.com/TerraFactory/tilegenerator/database/entities"
+)
+
+// PathPrimitive draws SVG path data "D" around a point. The path is drawn in pixels relative to the point,
+// it is rotated by the object azimuth if Rotate is true.
+type PathPrimitive struct {
+	Paint
+	D      string
+	Rotate bool
+}
+
+func (path PathPrimitive) Render(svg *svg.SVG, object *entities.MapObject, zoom int) {
+	point, err := object.Geometry.AsPoint()
+	if err != nil {
+		logf(svg, "Can't render PATH for object %v: %v \n", object.ID, err)
+		return
+	}
+
+	var angle float64
+	if path.Rotate {
+		angle = object.Azimut
+	}
+
+	paint := path.forObject(object)
+	patternID := paint.renderPattern(svg, object, zoom)
+	svg.TranslateRotate(int(math.Floor(point.Coordinates.X+.5)), int(math.Floor(point.Coordinates.Y+.5)), angle)
+	svg.Path(path.D, paint.style(patternID, true, zoom))
+	svg.Gend()
+}
+
+func NewPathPrimitive(params *map[string]interface{}) (PathPrimitive, error) {
+	path := PathPrimitive{Paint: newPaint()}
+	for key, value := range *params {
+		handled, err := path.setParam(key, value)
+		if !handled {
+			switch strings.ToUpper(key) {
+			case "D":
+				path.D, err = paramString(key, value)
+			case "ROTATE":
+				path.Rotate, err = paramBool(key, value)
+			}
+		}
+		if err != nil {
+			return path, err
+		}
+	}
+	return path, nil
+}
@@ -0,0 +1,66 @@
+package primitives
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TerraFactory/svgo"
+	"github.com/TerraFactory/tilegenerator/database/entities"
+	"github.com/TerraFactory/wktparser/geometry"
+)
+
+// PolygonPrimitive draws rings of a polygon or a multipolygon as one path, so holes are not filled
+type PolygonPrimitive struct {
+	Paint
+}
+
+func (polygon PolygonPrimitive) Render(svg *svg.SVG, object *entities.MapObject, zoom int) {
+	rings := geometryRings(object.Geometry)
+	if len(rings) == 0 {
+		return
+	}
+
+	paint := polygon.forObject(object)
+	patternID := paint.renderPattern(svg, object, zoom)
+	svg.Path(ringsToPath(rings), paint.style(patternID, true, zoom), `fill-rule="evenodd"`)
+}
+
+func NewPolygonPrimitive(params *map[string]interface{}) (PolygonPrimitive, error) {
+	polygon := PolygonPrimitive{Paint: newPaint()}
+	for key, value := range *params {
+		if _, err := polygon.setParam(key, value); err != nil {
+			return polygon, err
+		}
+	}
+	return polygon, nil
+}
+
+// geometryRings returns closed rings of the geometry. Rings are split at the point which closes each of them.
+func geometryRings(geo geometry.Geometry) [][]geometry.Coord {
+	rings := [][]geometry.Coord{}
+	current := []geometry.Coord{}
+	geo.ConvertCoords(func(x, y float64) (float64, float64) {
+		current = append(current, geometry.Coord{X: x, Y: y})
+		if len(current) > 1 && current[0] == current[len(current)-1] {
+			rings = append(rings, current)
+			current = []geometry.Coord{}
+		}
+		return x, y
+	})
+	if len(current) > 2 {
+		rings = append(rings, current)
+	}
+	return rings
+}
+
+func ringsToPath(rings [][]geometry.Coord) string {
+	parts := []string{}
+	for _, ring := range rings {
+		points := []string{}
+		for _, coord := range ring {
+			points = append(points, fmt.Sprintf("%v,%v", int(coord.X), int(coord.Y)))
+		}
+		parts = append(parts, "M"+strings.Join(points, " L")+" Z")
+	}
+	return strings.Join(parts, " ")
+}
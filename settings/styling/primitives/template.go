@@ -0,0 +1,187 @@
+package primitives
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/TerraFactory/tilegenerator/database/entities"
+)
+
+// Escaping defines how values are inserted into templates
+type Escaping int
+
+const (
+	// EscapeText inserts values as is, svg escapes text content itself
+	EscapeText Escaping = iota
+	// EscapeURL escapes values as parts of an URL path, so a value can't add path segments or change the scheme.
+	// The "raw" filter disables escaping, e.g. "${prop.url|raw}" for a property with the whole URL.
+	EscapeURL
+)
+
+// Variables of templates are fields of the object and the tile, and "prop.<name>" for properties of the object
+var templateVariables = map[string]func(object *entities.MapObject, zoom int) string{
+	"ID":          func(o *entities.MapObject, zoom int) string { return strconv.Itoa(o.ID) },
+	"id":          func(o *entities.MapObject, zoom int) string { return strconv.Itoa(o.ID) },
+	"label":       func(o *entities.MapObject, zoom int) string { return o.Label },
+	"code":        func(o *entities.MapObject, zoom int) string { return o.Code },
+	"type_id":     func(o *entities.MapObject, zoom int) string { return strconv.Itoa(o.TypeID) },
+	"style":       func(o *entities.MapObject, zoom int) string { return o.StyleName },
+	"position":    func(o *entities.MapObject, zoom int) string { return o.Position },
+	"size":        func(o *entities.MapObject, zoom int) string { return strconv.Itoa(o.Size) },
+	"scale":       func(o *entities.MapObject, zoom int) string { return formatFloat(o.Scale) },
+	"azimut":      func(o *entities.MapObject, zoom int) string { return formatFloat(o.Azimut) },
+	"color_outer": func(o *entities.MapObject, zoom int) string { return o.ColorOuter },
+	"color_inner": func(o *entities.MapObject, zoom int) string { return o.ColorInner },
+	"zoom":        func(o *entities.MapObject, zoom int) string { return strconv.Itoa(zoom) },
+}
+
+var templateFilters = map[string]bool{"upper": true, "lower": true, "round": true, "default": true, "raw": true}
+
+type templateFilter struct {
+	name, arg string
+}
+
+// templatePart is either a text or a variable with filters
+type templatePart struct {
+	text     string
+	variable string
+	filters  []templateFilter
+}
+
+type template []templatePart
+
+var parsedTemplates sync.Map
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// CheckTemplate returns an error if the template has unknown variables or filters
+func CheckTemplate(text string) error {
+	_, err := parseTemplate(text)
+	return err
+}
+
+// parseTemplate splits the template into parts. Parsed templates are cached.
+func parseTemplate(text string) (template, error) {
+	if cached, ok := parsedTemplates.Load(text); ok {
+		return cached.(template), nil
+	}
+	result := template{}
+	rest := text
+	for {
+		start := strings.Index(rest, "${")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(rest[start:], "}")
+		if end < 0 {
+			return nil, errors.New("Template variable is not closed: " + rest[start:])
+		}
+		if start > 0 {
+			result = append(result, templatePart{text: rest[:start]})
+		}
+		part, err := parseVariable(rest[start+2 : start+end])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, part)
+		rest = rest[start+end+1:]
+	}
+	if rest != "" {
+		result = append(result, templatePart{text: rest})
+	}
+	parsedTemplates.Store(text, result)
+	return result, nil
+}
+
+func parseVariable(expression string) (templatePart, error) {
+	items := strings.Split(expression, "|")
+	part := templatePart{variable: strings.TrimSpace(items[0])}
+	if _, ok := templateVariables[part.variable]; !ok && !strings.HasPrefix(part.variable, "prop.") {
+		return part, fmt.Errorf("Unknown template variable ${%s}", part.variable)
+	}
+	for _, item := range items[1:] {
+		filter := templateFilter{name: strings.TrimSpace(item)}
+		if i := strings.Index(item, ":"); i >= 0 {
+			filter = templateFilter{name: strings.TrimSpace(item[:i]), arg: item[i+1:]}
+		}
+		if !templateFilters[filter.name] {
+			return part, fmt.Errorf("Unknown template filter %s of ${%s}", filter.name, expression)
+		}
+		if filter.name == "round" {
+			if _, err := strconv.Atoi(filter.arg); err != nil {
+				return part, fmt.Errorf("round of ${%s} should have a number of digits", expression)
+			}
+		}
+		part.filters = append(part.filters, filter)
+	}
+	return part, nil
+}
+
+// value returns the variable of the object with applied filters and true if it should not be escaped
+func (part templatePart) value(object *entities.MapObject, zoom int) (string, bool) {
+	var value string
+	if strings.HasPrefix(part.variable, "prop.") {
+		value, _ = object.Properties.String(strings.TrimPrefix(part.variable, "prop."))
+	} else {
+		value = templateVariables[part.variable](object, zoom)
+	}
+	raw := false
+	for _, filter := range part.filters {
+		switch filter.name {
+		case "upper":
+			value = strings.ToUpper(value)
+		case "lower":
+			value = strings.ToLower(value)
+		case "round":
+			if number, err := strconv.ParseFloat(value, 64); err == nil {
+				digits, _ := strconv.Atoi(filter.arg)
+				value = strconv.FormatFloat(number, 'f', digits, 64)
+			}
+		case "default":
+			if value == "" {
+				value = filter.arg
+			}
+		case "raw":
+			raw = true
+		}
+	}
+	return value, raw
+}
+
+// ExpandTemplate replaces variables like "${label}" or "${prop.speed|round:1|default:?}" with values of the object.
+// Filters are applied to the value from left to right:
+//
+//	upper, lower - change case;
+//	round:N - formats a number with N digits after the point, other values are kept;
+//	default:text - replaces an empty value;
+//	raw - disables escaping of the value.
+//
+// Templates with errors are returned as is.
+func ExpandTemplate(text string, object *entities.MapObject, zoom int, escape Escaping) string {
+	if !strings.Contains(text, "${") {
+		return text
+	}
+	parts, err := parseTemplate(text)
+	if err != nil {
+		return text
+	}
+	result := ""
+	for _, part := range parts {
+		if part.variable == "" {
+			result += part.text
+			continue
+		}
+		value, raw := part.value(object, zoom)
+		if escape == EscapeURL && !raw {
+			value = url.PathEscape(value)
+		}
+		result += value
+	}
+	return result
+}
@@ -0,0 +1,40 @@
+package primitives
+
+import (
+	"testing"
+
+	"github.com/TerraFactory/tilegenerator/database/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandTemplate(t *testing.T) {
+	object := &entities.MapObject{
+		ID:         7,
+		TypeID:     3,
+		Code:       "sfgpucaa",
+		Azimut:     45.5,
+		ColorOuter: "red",
+		Properties: entities.Properties{"speed": 12.345, "name": "Red square", "icon": "https://example.com/a b.png"},
+	}
+
+	assert.Equal(t, "SFGPUCAA 7/3 z10", ExpandTemplate("${code|upper} ${ID}/${type_id} z${zoom}", object, 10, EscapeText))
+	assert.Equal(t, "12.3 km/h", ExpandTemplate("${prop.speed|round:1} km/h", object, 10, EscapeText))
+	assert.Equal(t, "?", ExpandTemplate("${prop.missing|default:?}", object, 10, EscapeText))
+	assert.Equal(t, "45.5 red", ExpandTemplate("${azimut} ${color_outer}", object, 10, EscapeText))
+
+	assert.Equal(t, "asset://mil/Red%20square.svg", ExpandTemplate("asset://mil/${prop.name}.svg", object, 10, EscapeURL))
+	assert.Equal(t, "asset://mil/Red square.svg", ExpandTemplate("asset://mil/${prop.name|raw}.svg", object, 10, EscapeURL))
+	assert.Equal(t, "https:%2F%2Fexample.com%2Fa%20b.png", ExpandTemplate("${prop.icon}", object, 10, EscapeURL))
+	assert.Equal(t, "https://example.com/a b.png", ExpandTemplate("${prop.icon|raw}", object, 10, EscapeURL))
+}
+
+func TestCheckTemplate(t *testing.T) {
+	assert.Nil(t, CheckTemplate("${label} ${prop.name|lower|default:unknown}"))
+	assert.NotNil(t, CheckTemplate("${unknown}"))
+	assert.NotNil(t, CheckTemplate("${label|bold}"))
+	assert.NotNil(t, CheckTemplate("${prop.speed|round:x}"))
+	assert.NotNil(t, CheckTemplate("${label"))
+
+	_, err := NewTextPrimitive(&map[string]interface{}{"Content": "${lable}"})
+	assert.Equal(t, "Content", err.(*ParamError).Key)
+}
@@ -1,74 +1,300 @@
 package primitives
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/TerraFactory/svgo"
 	"github.com/TerraFactory/tilegenerator/database/entities"
 )
 
+// Symbol is a primitive drawn around the point of an object, e.g. an image. Labels are placed around its box.
+type Symbol interface {
+	Size(canvas *svg.SVG, object *entities.MapObject, zoom int) (width, height float64)
+}
+
+// Positions of labels relative to the symbol box
+var textPositions = map[string]bool{
+	"center": true, "top": true, "bottom": true, "left": true, "right": true,
+	"top-left": true, "top-right": true, "bottom-left": true, "bottom-right": true,
+}
+
+// TextPrimitive draws a label of the object. Content is a template like "${label}" (see ExpandTemplate),
+// Fallback is used instead of it when the expanded Content is empty. Nothing is drawn if the text is empty.
+// Lines are split by "\n" and wrapped by words to MaxWidth pixels. Halo is an outline around letters,
+// Background is a box under the text.
+// Label is placed at Position around the box of Symbol with Padding between them, "${position}" is replaced
+// with the position of the object. Without Position the text is anchored at the point by Anchor.
+// Text extents are measured with metrics of FontFile or embedded metrics of a sans-serif font,
+// a relative FontFile is a path in the styles directory.
 type TextPrimitive struct {
-	Weight   int64
-	Size     int64
-	Style    string
-	Position string
-	Content  string
+	FontFamily        string
+	Weight            int64
+	Size              ZoomValue
+	Style             string
+	Color             string
+	Opacity           float64
+	LetterSpacing     float64
+	Anchor            string
+	HaloColor         string
+	HaloWidth         float64
+	Background        string
+	BackgroundOpacity float64
+	BackgroundPadding float64
+	MaxWidth          float64
+	LineHeight        float64
+	Position          string
+	Padding           float64
+	Content           string
+	Fallback          string
+	FontFile          string
+	Symbol            Symbol
+	metrics           *FontMetrics
+}
+
+// textBlock is a placement of lines: "x" of the anchor, baseline "y" of the first line and the box of lines
+type textBlock struct {
+	x, y          float64
+	anchor        string
+	left, top     float64
+	width, height float64
+}
+
+// content returns text of the label for the object, Fallback is used if the expanded Content is empty
+func (text TextPrimitive) content(object *entities.MapObject, zoom int) string {
+	content := strings.TrimSpace(ExpandTemplate(text.Content, object, zoom, EscapeText))
+	if content == "" {
+		content = strings.TrimSpace(ExpandTemplate(text.Fallback, object, zoom, EscapeText))
+	}
+	return content
+}
+
+func (text TextPrimitive) fontMetrics() *FontMetrics {
+	if text.metrics != nil {
+		return text.metrics
+	}
+	return defaultFontMetrics(text.Weight)
+}
+
+// textWidth returns width of the line in pixels
+func (text TextPrimitive) textWidth(line string, size float64) float64 {
+	return text.fontMetrics().Width(line, size, text.LetterSpacing)
+}
+
+// lines splits content by line breaks and wraps lines which are wider than MaxWidth
+func (text TextPrimitive) lines(content string, size float64) []string {
+	result := []string{}
+	for _, paragraph := range strings.Split(content, "\n") {
+		words := strings.Fields(paragraph)
+		if text.MaxWidth <= 0 || len(words) == 0 {
+			result = append(result, strings.TrimSpace(paragraph))
+			continue
+		}
+		line := words[0]
+		for _, word := range words[1:] {
+			if text.textWidth(line+" "+word, size) > text.MaxWidth {
+				result = append(result, line)
+				line = word
+			} else {
+				line += " " + word
+			}
+		}
+		result = append(result, line)
+	}
+	return result
+}
+
+func (text TextPrimitive) style(size float64) string {
+	style := fmt.Sprintf("font-family:%s; font-size:%vpx; fill:%s; fill-opacity:%v; text-anchor:%s;",
+		text.FontFamily, size, text.Color, text.Opacity, text.Anchor)
+	if text.Weight > 0 {
+		style += fmt.Sprintf(" font-weight:%v;", text.Weight)
+	}
+	if text.Style != "" {
+		style += fmt.Sprintf(" font-style:%s;", text.Style)
+	}
+	if text.LetterSpacing != 0 {
+		style += fmt.Sprintf(" letter-spacing:%vpx;", text.LetterSpacing)
+	}
+	if text.HaloWidth > 0 {
+		style += fmt.Sprintf(" stroke:%s; stroke-width:%v; stroke-linejoin:round; paint-order:stroke;", text.HaloColor, text.HaloWidth*2)
+	}
+	return style
 }
 
-func (text TextPrimitive) Render(svg *svg.SVG, object *entities.MapObject) {
-	point, _ := object.Geometry.AsPoint()
-	//Temporary solution. I used static shift value because we need to know image size, but we don't know it
-	// we have to move such primitives as labels and so on into the image primitive
-	var xShift float64
-	var yShift float64
-
-	if object.Label != "" {
-		text.Content = strings.Replace(text.Content, "${label}", object.Label, 1)
-	} else {
-		text.Content = ""
-	}
-
-	if object.Position != "" {
-		text.Position = strings.Replace(text.Position, "${position}", object.Position, 1)
-	} else {
-		text.Position = "Bottom"
-	}
-
-	switch text.Position {
-	case "top":
-		xShift = -20
-		yShift = -40
-	case "bottom":
-		xShift = -20
-		yShift = 40
-	case "left":
-		xShift = -60
-		yShift = 10
-	case "right":
-		xShift = 20
-		yShift = 10
-	}
-
-	svg.Text(
-		int(point.Coordinates.X+xShift),
-		int(point.Coordinates.Y+yShift),
-		text.Content)
+// place returns placement of lines around the symbol box of "symbolWidth" x "symbolHeight" centered at (x, y)
+func (text TextPrimitive) place(x, y, symbolWidth, symbolHeight float64, position string, lines []string, size float64) textBlock {
+	metrics := text.fontMetrics()
+	block := textBlock{anchor: "middle"}
+	for _, line := range lines {
+		if w := text.textWidth(line, size); w > block.width {
+			block.width = w
+		}
+	}
+	block.height = (metrics.Ascent+metrics.Descent)*size + float64(len(lines)-1)*size*text.LineHeight
+
+	if position == "" {
+		block.x, block.y, block.anchor = x, y, text.Anchor
+		block.top = y - metrics.Ascent*size
+		switch text.Anchor {
+		case "start":
+			block.left = x
+		case "middle":
+			block.left = x - block.width/2
+		case "end":
+			block.left = x - block.width
+		}
+		return block
+	}
+
+	switch {
+	case strings.HasSuffix(position, "left"):
+		block.anchor = "end"
+		block.x = x - symbolWidth/2 - text.Padding
+		block.left = block.x - block.width
+	case strings.HasSuffix(position, "right"):
+		block.anchor = "start"
+		block.x = x + symbolWidth/2 + text.Padding
+		block.left = block.x
+	default:
+		block.x = x
+		block.left = x - block.width/2
+	}
+	switch {
+	case strings.HasPrefix(position, "top"):
+		block.top = y - symbolHeight/2 - text.Padding - block.height
+	case strings.HasPrefix(position, "bottom"):
+		block.top = y + symbolHeight/2 + text.Padding
+	default:
+		block.top = y - block.height/2
+	}
+	block.y = block.top + metrics.Ascent*size
+	return block
+}
+
+func (text TextPrimitive) Render(svg *svg.SVG, object *entities.MapObject, zoom int) {
+	point, err := object.Geometry.AsPoint()
+	if err != nil {
+		logf(svg, "Can't render TEXT for object %v: %v \n", object.ID, err)
+		return
+	}
+
+	position := text.Position
+	if strings.Contains(position, "${position}") {
+		objectPosition := object.Position
+		if objectPosition == "" {
+			objectPosition = "bottom"
+		}
+		position = strings.Replace(position, "${position}", objectPosition, -1)
+	}
+
+	var symbolWidth, symbolHeight float64
+	if text.Symbol != nil {
+		symbolWidth, symbolHeight = text.Symbol.Size(svg, object, zoom)
+	}
+	text.RenderAt(svg, point.Coordinates.X, point.Coordinates.Y, symbolWidth, symbolHeight, text.content(object, zoom), position, zoom)
+}
+
+// RenderAt draws the content around the symbol box centered at (x, y). Unknown positions are drawn at the center.
+func (text TextPrimitive) RenderAt(svg *svg.SVG, x, y, symbolWidth, symbolHeight float64, content, position string, zoom int) {
+	if content == "" {
+		return
+	}
+	position = strings.ToLower(position)
+	if position != "" && !textPositions[position] {
+		position = "center"
+	}
+
+	size := text.Size.At(zoom)
+	lines := text.lines(content, size)
+	block := text.place(x, y, symbolWidth, symbolHeight, position, lines, size)
+	if text.Background != "" {
+		padding := text.BackgroundPadding
+		svg.Rect(int(block.left-padding), int(block.top-padding), int(block.width+padding*2), int(block.height+padding*2),
+			fmt.Sprintf("fill:%s; fill-opacity:%v", text.Background, text.BackgroundOpacity))
+	}
+	text.Anchor = block.anchor
+	svg.Gstyle(text.style(size))
+	for i, line := range lines {
+		svg.Text(int(block.x), int(block.y+float64(i)*size*text.LineHeight), line)
+	}
+	svg.Gend()
 }
 
 func NewTextPrimitive(params *map[string]interface{}) (TextPrimitive, error) {
-	text := TextPrimitive{}
+	text := TextPrimitive{
+		FontFamily:        "sans-serif",
+		Size:              Constant(12),
+		Color:             "black",
+		Opacity:           1,
+		Anchor:            "start",
+		HaloColor:         "white",
+		BackgroundOpacity: 1,
+		BackgroundPadding: 2,
+		LineHeight:        1.2,
+		Padding:           2,
+	}
 	for key, value := range *params {
+		var err error
 		switch strings.ToUpper(key) { // Switch here is temporary workaround. I should use reflect instead.
+		case "FONTFAMILY", "FONT":
+			text.FontFamily, err = paramString(key, value)
 		case "SIZE":
-			text.Size = value.(int64)
+			text.Size, err = paramZoomValue(key, value)
 		case "WEIGHT":
-			text.Weight = value.(int64)
+			text.Weight, err = paramInt(key, value)
 		case "STYLE":
-			text.Style = value.(string)
+			text.Style, err = paramString(key, value)
+		case "COLOR", "FILL":
+			text.Color, err = paramString(key, value)
+		case "OPACITY":
+			text.Opacity, err = paramFloat(key, value)
+		case "LETTERSPACING":
+			text.LetterSpacing, err = paramFloat(key, value)
+		case "ANCHOR":
+			text.Anchor, err = paramString(key, value)
+			if err == nil && text.Anchor != "start" && text.Anchor != "middle" && text.Anchor != "end" {
+				err = paramErrorf(key, "%s should be start, middle or end, got %v", key, value)
+			}
+		case "HALOCOLOR":
+			text.HaloColor, err = paramString(key, value)
+		case "HALOWIDTH":
+			text.HaloWidth, err = paramFloat(key, value)
+		case "BACKGROUND":
+			text.Background, err = paramString(key, value)
+		case "BACKGROUNDOPACITY":
+			text.BackgroundOpacity, err = paramFloat(key, value)
+		case "BACKGROUNDPADDING":
+			text.BackgroundPadding, err = paramFloat(key, value)
+		case "MAXWIDTH":
+			text.MaxWidth, err = paramFloat(key, value)
+		case "LINEHEIGHT":
+			text.LineHeight, err = paramFloat(key, value)
 		case "POSITION":
-			text.Position = value.(string)
+			text.Position, err = paramString(key, value)
+			if err == nil && !strings.Contains(text.Position, "${position}") && !textPositions[strings.ToLower(text.Position)] {
+				err = paramErrorf(key, "Unknown position %s", text.Position)
+			}
+		case "PADDING":
+			text.Padding, err = paramFloat(key, value)
+		case "FONTFILE":
+			if text.FontFile, err = paramString(key, value); err == nil {
+				text.metrics, err = LoadFontMetrics(text.FontFile)
+				if err != nil {
+					err = paramErrorf(key, "%v", err)
+				}
+			}
 		case "CONTENT":
-			text.Content = value.(string)
+			if text.Content, err = paramString(key, value); err == nil {
+				err = paramTemplate(key, text.Content)
+			}
+		case "FALLBACK":
+			if text.Fallback, err = paramString(key, value); err == nil {
+				err = paramTemplate(key, text.Fallback)
+			}
+		}
+		if err != nil {
+			return text, err
 		}
 	}
 
@@ -0,0 +1,61 @@
+package primitives
+
+import (
+	"testing"
+
+	"github.com/TerraFactory/tilegenerator/database/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTextPrimitive_Content(t *testing.T) {
+	text, err := NewTextPrimitive(&map[string]interface{}{"Content": "${label}", "Fallback": "${prop.name}"})
+	assert.Nil(t, err)
+
+	assert.Equal(t, "Airbase", text.content(&entities.MapObject{Label: "Airbase"}, 10))
+	assert.Equal(t, "Kubinka", text.content(&entities.MapObject{Properties: entities.Properties{"name": "Kubinka"}}, 10))
+	assert.Equal(t, "", text.content(&entities.MapObject{}, 10))
+
+	/* Content without ${label} is drawn for objects without a label */
+	text, err = NewTextPrimitive(&map[string]interface{}{"Content": "${code}"})
+	assert.Nil(t, err)
+	assert.Equal(t, "1000000004", text.content(&entities.MapObject{Code: "1000000004"}, 10))
+	text, err = NewTextPrimitive(&map[string]interface{}{"Content": "${prop.name}", "Fallback": "${code}"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Kubinka", text.content(&entities.MapObject{Code: "1", Properties: entities.Properties{"name": "Kubinka"}}, 10))
+	assert.Equal(t, "1", text.content(&entities.MapObject{Code: "1"}, 10))
+}
+
+func TestTextPrimitive_Lines(t *testing.T) {
+	text, err := NewTextPrimitive(&map[string]interface{}{"MaxWidth": int64(40)})
+	assert.Nil(t, err)
+	text.metrics = &FontMetrics{defaultWidth: 0.6}
+
+	// 10px font has 6px wide characters, so 6 characters fit in 40px
+	assert.Equal(t, []string{"ab cd", "efghij", "k"}, text.lines("ab cd efghij k", 10))
+	assert.Equal(t, []string{"first", "second"}, text.lines("first\nsecond", 10))
+
+	_, err = NewTextPrimitive(&map[string]interface{}{"Anchor": "left"})
+	assert.NotNil(t, err)
+}
+
+func TestTextPrimitive_Place(t *testing.T) {
+	text, err := NewTextPrimitive(&map[string]interface{}{"Padding": int64(4)})
+	assert.Nil(t, err)
+	text.metrics = &FontMetrics{Ascent: 0.8, Descent: 0.2, defaultWidth: 0.5}
+
+	// 24x24 symbol at (100, 100), "abcd" is 20x10 px
+	block := text.place(100, 100, 24, 24, "bottom", []string{"abcd"}, 10)
+	assert.Equal(t, textBlock{x: 100, y: 124, anchor: "middle", left: 90, top: 116, width: 20, height: 10}, block)
+
+	block = text.place(100, 100, 24, 24, "top-left", []string{"abcd"}, 10)
+	assert.Equal(t, textBlock{x: 84, y: 82, anchor: "end", left: 64, top: 74, width: 20, height: 10}, block)
+
+	block = text.place(100, 100, 24, 24, "right", []string{"abcd", "ab"}, 10)
+	assert.Equal(t, "start", block.anchor)
+	assert.Equal(t, 116.0, block.x)
+	assert.Equal(t, 22.0, block.height)
+	assert.Equal(t, 89.0, block.top)
+
+	_, err = NewTextPrimitive(&map[string]interface{}{"Position": "above"})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,77 @@
+package primitives
+
+import (
+	"sort"
+)
+
+// ZoomStop is a value of a parameter at a zoom level
+type ZoomStop struct {
+	Zoom  float64
+	Value float64
+}
+
+// ZoomValue is a numeric parameter which depends on zoom. Values between stops are interpolated linearly,
+// values outside of stops are equal to the nearest stop. A constant is a single stop.
+type ZoomValue []ZoomStop
+
+// Constant returns ZoomValue which is equal to "value" at every zoom
+func Constant(value float64) ZoomValue {
+	return ZoomValue{{Zoom: 0, Value: value}}
+}
+
+// At returns value of the parameter at the zoom
+func (zv ZoomValue) At(zoom int) float64 {
+	if len(zv) == 0 {
+		return 0
+	}
+	z := float64(zoom)
+	if z <= zv[0].Zoom {
+		return zv[0].Value
+	}
+	for i := 1; i < len(zv); i++ {
+		if z <= zv[i].Zoom {
+			prev, next := zv[i-1], zv[i]
+			return prev.Value + (next.Value-prev.Value)*(z-prev.Zoom)/(next.Zoom-prev.Zoom)
+		}
+	}
+	return zv[len(zv)-1].Value
+}
+
+// paramZoomValue reads a number or an array of [zoom, value] pairs, e.g. Width = [[5, 12], [10, 24]]
+func paramZoomValue(key string, value interface{}) (ZoomValue, error) {
+	stops, ok := value.([]interface{})
+	if !ok {
+		f, err := paramFloat(key, value)
+		if err != nil {
+			return nil, paramErrorf(key, "%s should be a number or an array of [zoom, value] pairs, got %v", key, value)
+		}
+		return Constant(f), nil
+	}
+
+	zv := ZoomValue{}
+	for _, stop := range stops {
+		pair, ok := stop.([]interface{})
+		if !ok || len(pair) != 2 {
+			return nil, paramErrorf(key, "%s should be an array of [zoom, value] pairs, got %v", key, value)
+		}
+		zoom, err := paramFloat(key, pair[0])
+		if err != nil {
+			return nil, err
+		}
+		v, err := paramFloat(key, pair[1])
+		if err != nil {
+			return nil, err
+		}
+		zv = append(zv, ZoomStop{Zoom: zoom, Value: v})
+	}
+	if len(zv) == 0 {
+		return nil, paramErrorf(key, "%s should contain at least one stop", key)
+	}
+	sort.Slice(zv, func(i, j int) bool { return zv[i].Zoom < zv[j].Zoom })
+	for i := 1; i < len(zv); i++ {
+		if zv[i].Zoom == zv[i-1].Zoom {
+			return nil, paramErrorf(key, "%s has two stops for zoom %v", key, zv[i].Zoom)
+		}
+	}
+	return zv, nil
+}
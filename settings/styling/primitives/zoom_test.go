@@ -0,0 +1,26 @@
+package primitives
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZoomValue_At(t *testing.T) {
+	zv, err := paramZoomValue("Width", []interface{}{
+		[]interface{}{int64(10), int64(24)},
+		[]interface{}{int64(5), 12.0},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 12.0, zv.At(0), "value below the first stop should be equal to the first stop")
+	assert.Equal(t, 12.0, zv.At(5))
+	assert.Equal(t, 19.2, zv.At(8))
+	assert.Equal(t, 24.0, zv.At(18))
+
+	zv, err = paramZoomValue("Width", int64(3))
+	assert.Nil(t, err)
+	assert.Equal(t, 3.0, zv.At(12))
+
+	_, err = paramZoomValue("Width", []interface{}{int64(3)})
+	assert.NotNil(t, err)
+}
@@ -4,32 +4,26 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"sync"
+	"path/filepath"
+	"sync/atomic"
 
-	"github.com/TerraFactory/tilegenerator/settings"
+	"github.com/TerraFactory/tilegenerator/assets"
+	"github.com/TerraFactory/tilegenerator/settings/styling/primitives"
 	"github.com/TerraFactory/tilegenerator/utils"
 	"github.com/TerraFactory/wktparser/geometry"
 	"github.com/pelletier/go-toml"
 	"strings"
 )
 
-var styles *map[string]Style
-var errs []error
-var once sync.Once
-
 func parseType(t string) (int, error) {
 	switch strings.ToUpper(t) {
 	case "POINT":
 		return geometry.TPoint, nil
 	case "MULTIPOINT":
 		return geometry.TMultiPoint, nil
-	case "LINE":
-	case "POLYLINE":
-	case "LINESTRING":
+	case "LINE", "POLYLINE", "LINESTRING":
 		return geometry.TLineString, nil
-	case "MULTILINE":
-	case "MULTIPOLYLINE":
-	case "MULTILINESTRING":
+	case "MULTILINE", "MULTIPOLYLINE", "MULTILINESTRING":
 		return geometry.TMultiLineString, nil
 	case "POLYGON":
 		return geometry.TPolygon, nil
@@ -37,62 +31,215 @@ func parseType(t string) (int, error) {
 	return -1, errors.New(fmt.Sprintf("Failed to parse geometry type %s", t))
 }
 
-func readStylesFile(filename string) (*Style, error) {
-	styles, err := toml.LoadFile(filename)
+// readDefinition reads a style or a fragment from the file
+func readDefinition(filename string) (*definition, []error) {
+	tree, err := toml.LoadFile(filename)
 	if err != nil {
-		return nil, err
+		return nil, []error{&Diagnostic{File: filename, Message: err.Error()}}
 	}
-	style := Style{}
-	style.Name = styles.Get("Name").(string)
-	geometryType, err := parseType(styles.Get("GeometryType").(string))
-	if err != nil {
-		return nil, err
+	def := definition{file: filename, tree: tree}
+	if name, ok := tree.Get("Name").(string); ok {
+		def.name = name
+	} else if name, ok := tree.Get("Fragment").(string); ok {
+		def.name = name
+		def.fragment = true
+	} else {
+		return nil, []error{&Diagnostic{File: filename, Key: "Name", Message: "Either Name or Fragment should be set"}}
 	}
-	style.GeometryType = geometryType
-	prims := styles.Get("primitives").([]*toml.TomlTree)
-	for _, p := range prims {
-		t := p.Get("Type").(string)
-		primitive, _ := NewPrimitive(t, p.ToMap())
-		style.Primitives = append(style.Primitives, primitive)
+	return &def, checkDefinition(&def)
+}
+
+// primitiveError locates an error returned by NewPrimitive
+func primitiveError(p rawPrimitive, err error) error {
+	if paramErr, ok := err.(*primitives.ParamError); ok {
+		if loc, ok := p.keys[paramErr.Key]; ok {
+			return loc.errorf(paramErr.Key, "%v", paramErr.Err)
+		}
+		return p.at.errorf(paramErr.Key, "%v", paramErr.Err)
 	}
-	return &style, nil
+	return p.at.errorf("", "%v", err)
 }
 
-func readStylesDirectory(directory string) (*map[string]Style, []error) {
-	result := map[string]Style{}
+// buildStyle creates a style with primitives from the flattened style
+func buildStyle(flat *flatStyle) (*Style, []error) {
+	errs := []error{}
+	style := Style{Name: flat.name, Priority: flat.priority, Else: flat.isElse}
+	if flat.geometryType == "" {
+		errs = append(errs, flat.at.errorf("GeometryType", "GeometryType should be set"))
+	} else if geometryType, err := parseType(flat.geometryType); err == nil {
+		style.GeometryType = geometryType
+	} else {
+		errs = append(errs, flat.geometryTypeAt.errorf("GeometryType", "%v", err))
+	}
+	if flat.parsedFilter != nil {
+		style.Filter = flat.parsedFilter
+	} else if flat.filter != nil {
+		filter, err := readFilter(flat.filter)
+		if err == nil {
+			style.Filter = filter
+		} else {
+			errs = append(errs, flat.filterAt.errorf("filter", "%v", err))
+		}
+	}
+	for _, p := range flat.primitives {
+		t, _ := p.params["Type"].(string)
+		primitive, err := NewPrimitive(t, p.params)
+		if err == nil {
+			style.Primitives = append(style.Primitives, primitive)
+		} else {
+			errs = append(errs, primitiveError(p, err))
+		}
+	}
+	attachLabels(style.Primitives)
+	return &style, errs
+}
+
+// GraphicsFile of the styles directory maps object codes to tactical graphics, it is not a style
+const GraphicsFile = "graphics.toml"
+
+// readDefinitions reads all *.toml files of the directory and its subdirectories except GraphicsFile.
+// *.json files are MapLibre styles, they are imported as flattened styles.
+func readDefinitions(directory string) ([]*definition, []*flatStyle, []error) {
+	definitions := []*definition{}
+	imported := []*flatStyle{}
 	allErrors := []error{}
 
-	fmt.Println(directory)
-	if utils.IsDirectory(directory) {
-		return nil, []error{fmt.Errorf("Path %s is not a directory", directory)}
-	}
 	files, err := ioutil.ReadDir(directory)
 	if err != nil {
-		return nil, []error{err}
+		return nil, nil, []error{&Diagnostic{File: directory, Message: err.Error()}}
 	}
 	for _, file := range files {
 		path := directory + "/" + file.Name()
 		if file.IsDir() {
-			styles, errs := readStylesDirectory(path)
-			for _, style := range *styles {
-				result[style.Name] = style
+			defs, flats, errs := readDefinitions(path)
+			definitions = append(definitions, defs...)
+			imported = append(imported, flats...)
+			allErrors = append(allErrors, errs...)
+		} else if file.Name() == GraphicsFile {
+			continue
+		} else if strings.HasSuffix(file.Name(), ".toml") {
+			def, errs := readDefinition(path)
+			if def != nil {
+				definitions = append(definitions, def)
 			}
 			allErrors = append(allErrors, errs...)
-		} else {
-			style, err := readStylesFile(path)
-			if err == nil {
-				result[style.Name] = *style
-			} else {
-				allErrors = append(allErrors, err)
+		} else if strings.HasSuffix(file.Name(), ".json") {
+			flats, errs := readMapLibreFile(path)
+			imported = append(imported, flats...)
+			allErrors = append(allErrors, errs...)
+		}
+	}
+	return definitions, imported, allErrors
+}
+
+// loadStyles reads and resolves all styles of the directory. Styles with errors are skipped, warnings are returned
+// with errors.
+// Flattened styles are returned as well, so they can be validated further.
+func loadStyles(directory string) (map[string]Style, []*flatStyle, []error) {
+	result := map[string]Style{}
+	flats := []*flatStyle{}
+
+	if !utils.IsDirectory(directory) {
+		return result, flats, []error{&Diagnostic{File: directory, Message: "Path is not a directory"}}
+	}
+	definitions, imported, allErrors := readDefinitions(directory)
+	r, errs := newResolver(definitions)
+	allErrors = append(allErrors, errs...)
+
+	for name := range r.styles {
+		flat, err := r.resolveStyle(name, nil)
+		if err != nil {
+			allErrors = append(allErrors, err)
+			continue
+		}
+		flats = append(flats, flat)
+	}
+	declared := map[string]string{}
+	for name, def := range r.styles {
+		declared[name] = def.file
+	}
+	for _, flat := range imported {
+		if file, ok := declared[flat.name]; ok {
+			allErrors = append(allErrors, flat.at.errorf("layers."+flat.name, "Style %s is already declared in %s", flat.name, file))
+			continue
+		}
+		declared[flat.name] = flat.at.file
+		flats = append(flats, flat)
+	}
+
+	for _, flat := range flats {
+		resolveHrefs(flat, directory)
+		if flat.abstract {
+			continue
+		}
+		style, errs := buildStyle(flat)
+		allErrors = append(allErrors, errs...)
+		if HasErrors(errs) {
+			continue
+		}
+		result[style.Name] = *style
+	}
+	return result, flats, sortErrors(allErrors)
+}
+
+// resolveHrefs makes relative hrefs and fallbacks of IMAGE primitives and font files of TEXT primitives
+// relative to the styles directory. Hrefs built from object data are resolved while rendering,
+// Directory of primitives is set for them.
+func resolveHrefs(flat *flatStyle, directory string) {
+	for _, p := range flat.primitives {
+		t, _ := p.params["Type"].(string)
+		if t == "TEXT" {
+			for key, value := range p.params {
+				if path, ok := value.(string); ok && strings.ToUpper(key) == "FONTFILE" && path != "" && !filepath.IsAbs(path) {
+					p.params[key] = filepath.Join(directory, path)
+				}
+			}
+		}
+		if t != "IMAGE" {
+			continue
+		}
+		p.params["Directory"] = directory
+		for key, value := range p.params {
+			if href, ok := value.(string); ok && isHrefKey(key) && href != "none" {
+				p.params[key] = assets.Resolve(href, directory)
 			}
 		}
 	}
-	return &result, allErrors
 }
 
-func GetStyles(conf *settings.Settings) (*map[string]Style, []error) {
-	once.Do(func() {
-		styles, errs = readStylesDirectory(conf.StylesDirectory)
-	})
-	return styles, errs
+func isHrefKey(key string) bool {
+	key = strings.ToUpper(key)
+	return key == "HREF" || key == "FALLBACK"
+}
+
+// ReadStyles reads styles from the directory and returns them with problems found while reading
+func ReadStyles(directory string) (*map[string]Style, []error) {
+	result, _, errs := loadStyles(directory)
+	return &result, errs
+}
+
+// StyleSet keeps the current styles of a renderer. Reloading replaces them as a whole,
+// so they can be used by concurrent renders.
+type StyleSet struct {
+	current atomic.Value
+}
+
+// NewStyleSet creates a set of the styles
+func NewStyleSet(styles *map[string]Style) *StyleSet {
+	set := StyleSet{}
+	set.current.Store(styles)
+	return &set
+}
+
+// Styles returns the current styles. Returned map must not be modified.
+func (set *StyleSet) Styles() *map[string]Style {
+	styles, _ := set.current.Load().(*map[string]Style)
+	return styles
+}
+
+// replace sets new styles and returns previous ones
+func (set *StyleSet) replace(styles *map[string]Style) *map[string]Style {
+	previous := set.Styles()
+	set.current.Store(styles)
+	return previous
 }
@@ -3,38 +3,167 @@ package styling
 import (
 	"errors"
 	"fmt"
+	"math"
+	"sort"
+	"strings"
+
 	"github.com/TerraFactory/svgo"
 	"github.com/TerraFactory/tilegenerator/database/entities"
 	"github.com/TerraFactory/tilegenerator/settings/styling/primitives"
 )
 
 type Primitive interface {
-	Render(svg *svg.SVG, object *entities.MapObject)
+	Render(svg *svg.SVG, object *entities.MapObject, zoom int)
+}
+
+// zoomRangePrimitive renders the primitive only on zoom levels from minZoom to maxZoom inclusive
+type zoomRangePrimitive struct {
+	Primitive
+	minZoom, maxZoom int64
+}
+
+func (p zoomRangePrimitive) Render(svg *svg.SVG, object *entities.MapObject, zoom int) {
+	if int64(zoom) >= p.minZoom && int64(zoom) <= p.maxZoom {
+		p.Primitive.Render(svg, object, zoom)
+	}
 }
 
+// Style describes how objects are rendered. Objects are selected by Filter, styles with higher Priority are checked first.
+// Else styles are used for objects which are not matched by any other style.
+// Style without a filter matches objects by StyleName. Else style without a filter matches objects having any StyleName,
+// so objects of the special layer, which are drawn by tactical graphics, are not drawn by it twice.
 type Style struct {
 	GeometryType int
 	Name         string
+	Priority     int64
+	Filter       *Filter
+	Else         bool
 	Primitives   []Primitive
 }
 
 func (style *Style) ShouldRender(object *entities.MapObject) bool {
-	return style.GeometryType == object.Geometry.GetType() && style.Name == object.StyleName
+	if style.GeometryType != object.Geometry.GetType() {
+		return false
+	}
+	if style.Filter != nil {
+		return style.Filter.Matches(object)
+	}
+	return style.Name == object.StyleName || (style.Else && object.StyleName != "")
+}
+
+// SortStyles returns styles in the order they should be evaluated: by priority, "else" styles are the last ones
+func SortStyles(styles *map[string]Style) []*Style {
+	sorted := []*Style{}
+	for name := range *styles {
+		style := (*styles)[name]
+		sorted = append(sorted, &style)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Else != b.Else {
+			return !a.Else
+		}
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		return a.Name < b.Name
+	})
+	return sorted
+}
+
+// MatchStyle returns the first style from sorted styles which should render the object or nil if there is no such style
+func MatchStyle(sorted []*Style, object *entities.MapObject) *Style {
+	for _, style := range sorted {
+		if style.ShouldRender(object) {
+			return style
+		}
+	}
+	return nil
 }
 
-func (s *Style) Render(object *entities.MapObject, canvas *svg.SVG) {
+func (s *Style) Render(object *entities.MapObject, canvas *svg.SVG, zoom int) {
 	for _, p := range s.Primitives {
-		p.Render(canvas, object)
+		p.Render(canvas, object, zoom)
+	}
+}
+
+// attachLabels places TEXT primitives around the first symbol primitive of the style, e.g. IMAGE
+func attachLabels(styled []Primitive) {
+	var symbol primitives.Symbol
+	for _, p := range styled {
+		if ranged, ok := p.(zoomRangePrimitive); ok {
+			p = ranged.Primitive
+		}
+		if s, ok := p.(primitives.Symbol); ok {
+			symbol = s
+			break
+		}
+	}
+	if symbol == nil {
+		return
+	}
+	for i, p := range styled {
+		switch v := p.(type) {
+		case primitives.TextPrimitive:
+			v.Symbol = symbol
+			styled[i] = v
+		case zoomRangePrimitive:
+			if text, ok := v.Primitive.(primitives.TextPrimitive); ok {
+				text.Symbol = symbol
+				v.Primitive = text
+				styled[i] = v
+			}
+		}
 	}
 }
 
+// NewPrimitive creates a primitive of type "t". Optional MinZoom and MaxZoom parameters limit zoom levels
+// on which the primitive is rendered.
 func NewPrimitive(t string, params map[string]interface{}) (Primitive, error) {
+	zoomRange := zoomRangePrimitive{minZoom: 0, maxZoom: math.MaxInt32}
+	limited := false
+	for key, value := range params {
+		upperKey := strings.ToUpper(key)
+		if upperKey != "MINZOOM" && upperKey != "MAXZOOM" {
+			continue
+		}
+		zoom, ok := value.(int64)
+		if !ok {
+			return nil, &primitives.ParamError{Key: key, Err: fmt.Errorf("%s should be an integer, got %v", key, value)}
+		}
+		if upperKey == "MINZOOM" {
+			zoomRange.minZoom = zoom
+		} else {
+			zoomRange.maxZoom = zoom
+		}
+		limited = true
+	}
+
+	primitive, err := newPrimitive(t, params)
+	if err != nil || !limited {
+		return primitive, err
+	}
+	zoomRange.Primitive = primitive
+	return zoomRange, nil
+}
+
+func newPrimitive(t string, params map[string]interface{}) (Primitive, error) {
 	switch t {
 	case "TEXT":
 		return primitives.NewTextPrimitive(&params)
 	case "IMAGE":
 		return primitives.NewImagePrimitive(&params)
+	case "LINE":
+		return primitives.NewLinePrimitive(&params)
+	case "POLYGON":
+		return primitives.NewPolygonPrimitive(&params)
+	case "CIRCLE":
+		return primitives.NewCirclePrimitive(&params)
+	case "PATH":
+		return primitives.NewPathPrimitive(&params)
+	case "SYMBOL":
+		return primitives.NewMilitarySymbolPrimitive(&params)
 	default:
-		return nil, errors.New(fmt.Sprintf("Unknown primitive type %s.", t))
+		return nil, &primitives.ParamError{Key: "Type", Err: errors.New(fmt.Sprintf("Unknown primitive type %s.", t))}
 	}
 }
@@ -0,0 +1,90 @@
+package styling
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TerraFactory/svgo"
+	"github.com/TerraFactory/tilegenerator/database/entities"
+	"github.com/TerraFactory/wktparser/geometry"
+	"github.com/stretchr/testify/assert"
+)
+
+// testPoint is a point geometry of test objects
+type testPoint struct {
+	geometry.Geometry
+	x, y float64
+}
+
+func (p testPoint) GetType() int {
+	return geometry.TPoint
+}
+
+func (p testPoint) AsPoint() (*geometry.Point, error) {
+	return &geometry.Point{Coordinates: geometry.Coord{X: p.x, Y: p.y}}, nil
+}
+
+func TestMatchStyle_ByName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "styles")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	/* A style written before filters were added: no Filter, no Else */
+	ioutil.WriteFile(filepath.Join(dir, "home.toml"), []byte(`
+GeometryType = "POINT"
+Name = "home"
+
+[[primitives]]
+    Type = "TEXT"
+    Position = "top"
+    Weight = 700
+    Style = "cursive"
+    Size = 20
+    Content = "Home, Sweet Home!"
+`), 0644)
+
+	styles, _, errs := loadStyles(dir)
+	assert.Empty(t, errs)
+	sorted := SortStyles(&styles)
+
+	object := entities.MapObject{ID: 1, StyleName: "home", Geometry: testPoint{x: 10, y: 20}}
+	style := MatchStyle(sorted, &object)
+	assert.NotNil(t, style)
+
+	var result bytes.Buffer
+	style.Render(&object, svg.New(&result), 10)
+	assert.Contains(t, result.String(), "Home, Sweet Home!")
+
+	/* Objects without the style name, e.g. of the special layer, are not drawn by it */
+	assert.Nil(t, MatchStyle(sorted, &entities.MapObject{ID: 2, Geometry: testPoint{}}))
+}
+
+func TestLoadStyles_FontFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "styles")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "label.toml"), []byte(`
+GeometryType = "POINT"
+Name = "label"
+
+[[primitives]]
+    Type = "TEXT"
+    FontFile = "fonts/missing.ttf"
+`), 0644)
+
+	/* Font files are relative to the styles directory, not to the working directory */
+	_, _, errs := loadStyles(dir)
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), filepath.Join(dir, "fonts", "missing.ttf"))
+}
+
+func TestMatchStyle_Else(t *testing.T) {
+	styles := map[string]Style{"fallback": {Name: "fallback", GeometryType: geometry.TPoint, Else: true}}
+	sorted := SortStyles(&styles)
+
+	assert.NotNil(t, MatchStyle(sorted, &entities.MapObject{ID: 1, StyleName: "home", Geometry: testPoint{}}))
+	/* Objects of the special layer have no style name, they are drawn by tactical graphics */
+	assert.Nil(t, MatchStyle(sorted, &entities.MapObject{ID: 2, Geometry: testPoint{}}))
+}
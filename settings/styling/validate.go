@@ -0,0 +1,189 @@
+package styling
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TerraFactory/tilegenerator/assets"
+	"github.com/pelletier/go-toml"
+)
+
+// Timeout of requests made while checking image hrefs
+const hrefCheckTimeout = 10 * time.Second
+
+// Types of top-level keys of style files. Fragments may contain only "Fragment", "include" and "primitives".
+var definitionKeys = map[string]string{
+	"Name":         "a string",
+	"Fragment":     "a string",
+	"Extends":      "a string",
+	"GeometryType": "a string",
+	"Priority":     "an integer",
+	"Else":         "a boolean",
+	"Abstract":     "a boolean",
+	"filter":       "a table",
+	"include":      "an array of tables",
+	"primitives":   "an array of tables",
+}
+
+var fragmentKeys = []string{"Fragment", "include", "primitives"}
+
+// Keys of primitives in upper case. Keys of all types are "Type", "Id", "MinZoom" and "MaxZoom",
+// "Directory" of images is set to the directory of the style file.
+var commonPrimitiveKeys = []string{"TYPE", "ID", "MINZOOM", "MAXZOOM"}
+
+var paintKeys = []string{"STROKE", "STROKECOLOR", "STROKEWIDTH", "STROKEOPACITY", "DASHARRAY", "LINECAP", "LINEJOIN",
+	"FILL", "FILLCOLOR", "FILLOPACITY", "PATTERN", "PATTERNSIZE"}
+
+var primitiveKeys = map[string][]string{
+	"TEXT": {"FONTFAMILY", "FONT", "SIZE", "WEIGHT", "STYLE", "COLOR", "FILL", "OPACITY", "LETTERSPACING", "ANCHOR",
+		"HALOCOLOR", "HALOWIDTH", "BACKGROUND", "BACKGROUNDOPACITY", "BACKGROUNDPADDING", "MAXWIDTH", "LINEHEIGHT",
+		"POSITION", "PADDING", "FONTFILE", "CONTENT", "FALLBACK"},
+	"IMAGE":   {"WIDTH", "HEIGHT", "HREF", "ROTATE", "FORMAT", "FALLBACK", "DIRECTORY"},
+	"LINE":    paintKeys,
+	"POLYGON": paintKeys,
+	"CIRCLE":  append([]string{"RADIUS"}, paintKeys...),
+	"PATH":    append([]string{"D", "ROTATE"}, paintKeys...),
+	"SYMBOL":  {"CODE", "UNIQUEDESIGNATION", "HIGHERFORMATION", "ADDITIONALINFO", "SIZE", "FONTFAMILY", "FONT"},
+}
+
+func hasType(value interface{}, t string) bool {
+	switch value.(type) {
+	case string:
+		return t == "a string"
+	case int64:
+		return t == "an integer"
+	case bool:
+		return t == "a boolean"
+	case *toml.TomlTree:
+		return t == "a table"
+	case []*toml.TomlTree:
+		return t == "an array of tables"
+	}
+	return false
+}
+
+// checkDefinition reports unknown top-level keys and values of wrong types
+func checkDefinition(def *definition) []error {
+	errs := []error{}
+	for _, key := range def.tree.Keys() {
+		t, ok := definitionKeys[key]
+		if !ok || (def.fragment && !containsString(fragmentKeys, key)) {
+			errs = append(errs, def.at(key).errorf(key, "Unknown key"))
+			continue
+		}
+		if value := def.tree.Get(key); !hasType(value, t) {
+			errs = append(errs, def.at(key).errorf(key, "%s should be %s, got %v", key, t, value))
+		}
+	}
+	trees, _ := def.tree.Get("primitives").([]*toml.TomlTree)
+	for _, tree := range trees {
+		errs = append(errs, checkPrimitiveKeys(def.file, tree)...)
+	}
+	return errs
+}
+
+// checkPrimitiveKeys reports keys which are not parameters of the primitive type. A primitive without Type
+// overrides a primitive of a parent style by Id, so it may have keys of any type.
+// Unknown types are reported by NewPrimitive.
+func checkPrimitiveKeys(file string, tree *toml.TomlTree) []error {
+	errs := []error{}
+	t, hasType := tree.Get("Type").(string)
+	keys, known := primitiveKeys[t]
+	if hasType && !known {
+		return errs
+	}
+	for _, key := range tree.Keys() {
+		upperKey := strings.ToUpper(key)
+		if containsString(commonPrimitiveKeys, upperKey) {
+			continue
+		}
+		if hasType && !containsString(keys, upperKey) {
+			errs = append(errs, treeLocation(file, tree, key).errorf(key, "Unknown key of %s primitive", t))
+		} else if !hasType && !isPrimitiveKey(upperKey) {
+			errs = append(errs, treeLocation(file, tree, key).errorf(key, "Unknown key of primitives"))
+		}
+	}
+	return errs
+}
+
+func isPrimitiveKey(upperKey string) bool {
+	for _, keys := range primitiveKeys {
+		if containsString(keys, upperKey) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate reads the styles directory like ReadStyles does and returns all problems found in it.
+// Images are requested if "checkHrefs" is set, so unreachable hrefs are reported too.
+func Validate(directory string, checkHrefs bool) []error {
+	_, flats, errs := loadStyles(directory)
+	if checkHrefs {
+		errs = append(errs, checkImageHrefs(flats)...)
+	}
+	return sortErrors(errs)
+}
+
+// checkImageHrefs requests every href and fallback of IMAGE primitives once. Hrefs with templates like "${ID}" are skipped.
+func checkImageHrefs(flats []*flatStyle) []error {
+	errs := []error{}
+	checked := map[string]bool{}
+	client := http.Client{Timeout: hrefCheckTimeout}
+
+	sort.Slice(flats, func(i, j int) bool { return flats[i].name < flats[j].name })
+	for _, flat := range flats {
+		for _, p := range flat.primitives {
+			if t, _ := p.params["Type"].(string); t != "IMAGE" {
+				continue
+			}
+			for key, value := range p.params {
+				href, ok := value.(string)
+				if !isHrefKey(key) || !ok || href == "none" || strings.Contains(href, "${") || checked[href] {
+					continue
+				}
+				checked[href] = true
+				if err := checkHref(&client, href); err != nil {
+					errs = append(errs, p.keys[key].errorf(key, "%s is unreachable: %v", href, err))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+func checkHref(client *http.Client, href string) error {
+	switch {
+	case strings.HasPrefix(href, "data:"):
+		return nil
+	case strings.HasPrefix(href, "http://") || strings.HasPrefix(href, "https://"):
+		resp, err := client.Head(href)
+		if err == nil && resp.StatusCode == http.StatusMethodNotAllowed {
+			resp.Body.Close()
+			resp, err = client.Get(href)
+		}
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("HTTP %s", resp.Status)
+		}
+		return nil
+	default:
+		_, err := assets.Load(href)
+		return err
+	}
+}
@@ -0,0 +1,42 @@
+package styling
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "styles")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"good.toml": "Name = \"good\"\nGeometryType = \"POINT\"\n[[primitives]]\n  Type = \"CIRCLE\"\n",
+		"bad.toml":  "Name = \"bad\"\nGeometryType = \"SPHERE\"\nColor = 1\n[[primitives]]\n  Type = \"IMAGE\"\n  Width = \"big\"\n[[primitives]]\n  Type = \"SQUARE\"\n",
+		"keys.toml": "Name = \"keys\"\nGeometryType = \"POINT\"\n[[primitives]]\n  Type = \"CIRCLE\"\n  Radius = 3\n  Content = \"x\"\n[[primitives]]\n  Id = \"label\"\n  Colour = \"red\"\n",
+		"copy.toml": "Name = \"good\"\nGeometryType = \"POINT\"\n",
+	}
+	for name, content := range files {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	errs := Validate(dir, false)
+	messages := []string{}
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	bad := filepath.Join(dir, "bad.toml")
+	assert.Contains(t, messages, bad+":2: GeometryType: Failed to parse geometry type SPHERE")
+	assert.Contains(t, messages, bad+":3: Color: Unknown key")
+	assert.Contains(t, messages, bad+":6: Width: Width should be a number or an array of [zoom, value] pairs, got big")
+	assert.Contains(t, messages, bad+":8: Type: Unknown primitive type SQUARE.")
+	keys := filepath.Join(dir, "keys.toml")
+	assert.Contains(t, messages, keys+":6: Content: Unknown key of CIRCLE primitive")
+	assert.Contains(t, messages, keys+":9: Colour: Unknown key of primitives")
+	assert.Contains(t, messages, keys+":8: Type: Unknown primitive type .")
+	assert.Len(t, messages, 8)
+}
@@ -0,0 +1,141 @@
+package styling
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/TerraFactory/tilegenerator/settings/styling/primitives"
+)
+
+// Interval of checks of the styles directory for changes
+const watchPollInterval = time.Second
+
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// directorySnapshot returns sizes and modification times of all files in the directory
+func directorySnapshot(directory string) map[string]fileState {
+	snapshot := map[string]fileState{}
+	filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			snapshot[path] = fileState{size: info.Size(), modTime: info.ModTime()}
+		}
+		return nil
+	})
+	return snapshot
+}
+
+// WatchStyles checks the styles directory for changes and reloads styles of the set when files stop changing for "delay".
+// Problems are logged like on startup: if "strict" is set, new styles replace current ones only if they have no errors,
+// otherwise styles without errors are replaced and styles with errors keep their previous definitions.
+// "onReload" is called with previous and new styles after each replacement. Call returned function to stop watching.
+func WatchStyles(directory string, delay time.Duration, strict bool, set *StyleSet, onReload func(previous, styles *map[string]Style)) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(watchPollInterval)
+		defer ticker.Stop()
+
+		last := directorySnapshot(directory)
+		var changedAt time.Time
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				snapshot := directorySnapshot(directory)
+				if !reflect.DeepEqual(snapshot, last) {
+					last = snapshot
+					changedAt = now
+					continue
+				}
+				if !changedAt.IsZero() && now.Sub(changedAt) >= delay {
+					changedAt = time.Time{}
+					reloadStyles(directory, strict, set, onReload)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+func reloadStyles(directory string, strict bool, set *StyleSet, onReload func(previous, styles *map[string]Style)) {
+	primitives.ClearFontMetrics()
+	styles, errs := ReadStyles(directory)
+	if strict && HasErrors(errs) {
+		fmt.Printf("Styles are not reloaded, %v problems found:\n", len(errs))
+		log.Printf("Styles are not reloaded, %v problems found \n", len(errs))
+		for _, err := range errs {
+			fmt.Printf("\t%v\n", err)
+			log.Printf("Style error: %v \n", err)
+		}
+		return
+	}
+	for _, err := range errs {
+		if IsWarning(err) {
+			log.Printf("Style warning: %v \n", err)
+		} else {
+			fmt.Printf("\t%v\n", err)
+			log.Printf("Style error: %v \n", err)
+		}
+	}
+	if HasErrors(errs) {
+		styles = withPrevious(styles, set.Styles())
+	}
+	previous := set.replace(styles)
+	log.Printf("Styles are reloaded from %s, %v styles \n", directory, len(*styles))
+	if onReload != nil {
+		onReload(previous, styles)
+	}
+}
+
+// withPrevious adds previous definitions of styles missing from "styles", so styles broken by an edit are rendered
+// as before until their errors are fixed. Styles removed while other ones have errors are kept as well.
+func withPrevious(styles, previous *map[string]Style) *map[string]Style {
+	if previous == nil {
+		return styles
+	}
+	for name, style := range *previous {
+		if _, ok := (*styles)[name]; !ok {
+			(*styles)[name] = style
+		}
+	}
+	return styles
+}
+
+// ChangedStyles returns names of styles which are added, removed or changed. "matchingChanged" is true if
+// a style is added or its filter or priority is changed, so objects rendered by other styles may match it now
+// and tiles rendered without changed styles are affected as well.
+func ChangedStyles(previous, styles *map[string]Style) (names []string, matchingChanged bool) {
+	if previous == nil || styles == nil {
+		return nil, true
+	}
+	for name, style := range *styles {
+		old, ok := (*previous)[name]
+		if !ok {
+			names = append(names, name)
+			matchingChanged = true
+			continue
+		}
+		if old.GeometryType != style.GeometryType || old.Priority != style.Priority || old.Else != style.Else ||
+			!reflect.DeepEqual(old.Filter, style.Filter) {
+			matchingChanged = true
+		}
+		if !reflect.DeepEqual(old, style) {
+			names = append(names, name)
+		}
+	}
+	for name := range *previous {
+		if _, ok := (*styles)[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, matchingChanged
+}
@@ -0,0 +1,92 @@
+package styling
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TerraFactory/svgo"
+	"github.com/TerraFactory/tilegenerator/database/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangedStyles(t *testing.T) {
+	previous := map[string]Style{
+		"home":    {Name: "home", Else: true},
+		"route":   {Name: "route", Priority: 1},
+		"removed": {Name: "removed"},
+	}
+	styles := map[string]Style{
+		"home":  {Name: "home", Else: true},
+		"route": {Name: "route", Priority: 1, Primitives: []Primitive{nil}},
+	}
+	names, matchingChanged := ChangedStyles(&previous, &styles)
+	assert.Equal(t, []string{"removed", "route"}, names)
+	assert.False(t, matchingChanged)
+
+	styles["added"] = Style{Name: "added"}
+	names, matchingChanged = ChangedStyles(&previous, &styles)
+	assert.Equal(t, []string{"added", "removed", "route"}, names)
+	assert.True(t, matchingChanged)
+}
+
+func TestReloadStyles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "styles")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "home.toml"), []byte(`
+GeometryType = "POINT"
+Name = "home"
+
+[[primitives]]
+    Type = "TEXT"
+`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "broken.toml"), []byte(`
+GeometryType = "CIRCLE"
+Name = "broken"
+`), 0644)
+
+	set := NewStyleSet(&map[string]Style{})
+	reloaded := 0
+	onReload := func(previous, styles *map[string]Style) { reloaded++ }
+
+	reloadStyles(dir, true, set, onReload)
+	assert.Equal(t, 0, reloaded, "strict reload keeps current styles if some of them have errors")
+	assert.Empty(t, *set.Styles())
+
+	reloadStyles(dir, false, set, onReload)
+	assert.Equal(t, 1, reloaded)
+	_, ok := (*set.Styles())["home"]
+	assert.True(t, ok, "styles without errors are reloaded like on startup")
+	assert.Len(t, *set.Styles(), 1)
+
+	/* A style broken by an edit keeps rendering with its previous definition */
+	ioutil.WriteFile(filepath.Join(dir, "home.toml"), []byte(`
+GeometryType = "POINT"
+Name = "home"
+
+[[primitives]]
+    Type = "TEXT"
+    Content = "Home"
+`), 0644)
+	reloadStyles(dir, false, set, onReload)
+	ioutil.WriteFile(filepath.Join(dir, "home.toml"), []byte(`
+GeometryType = "POINT"
+Name = "home"
+
+[[primitives]]
+    Type = "TEXT"
+    Size = "big"
+`), 0644)
+	reloadStyles(dir, false, set, onReload)
+	assert.Equal(t, 3, reloaded)
+	sorted := SortStyles(set.Styles())
+	object := entities.MapObject{ID: 1, StyleName: "home", Geometry: testPoint{x: 10, y: 20}}
+	style := MatchStyle(sorted, &object)
+	assert.NotNil(t, style)
+	var result bytes.Buffer
+	style.Render(&object, svg.New(&result), 10)
+	assert.Contains(t, result.String(), "Home")
+}
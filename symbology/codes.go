@@ -0,0 +1,46 @@
+package symbology
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+)
+
+// Codes maps internal codes of objects to SIDC. Nil codes map nothing, only SIDC are looked up.
+type Codes map[string]string
+
+// LoadCodes reads the mapping of internal object codes to SIDC from the [codes] table of a TOML file
+func LoadCodes(path string) (Codes, error) {
+	tree, err := toml.LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	table, ok := tree.Get("codes").(*toml.TomlTree)
+	if !ok {
+		return nil, fmt.Errorf("%s should have a [codes] table", path)
+	}
+	codes := Codes{}
+	for _, code := range table.Keys() {
+		sidc, ok := table.Get(code).(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: SIDC of code %s should be a string", path, code)
+		}
+		if _, err := Parse(sidc); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		codes[code] = sidc
+	}
+	return codes, nil
+}
+
+// Lookup returns SIDC of the object code: the mapped one, or the code itself if it is a SIDC
+func (codes Codes) Lookup(code string) (string, bool) {
+	if sidc, ok := codes[code]; ok {
+		return sidc, true
+	}
+	if strings.TrimSpace(code) != "" && IsSIDC(code) {
+		return code, true
+	}
+	return "", false
+}
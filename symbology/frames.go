@@ -0,0 +1,123 @@
+package symbology
+
+import "fmt"
+
+// box is an area of the frame where the icon is drawn: center and half sizes
+type box struct {
+	x, y, w, h float64
+}
+
+// frame is the outline of the symbol centered at (0, 0) with its extents, icon area and the point
+// where the headquarters staff starts
+type frame struct {
+	path           string
+	top, bottom    float64
+	halfWidth      float64
+	icon           box
+	staffX, staffY float64
+}
+
+// frame returns the frame shape by affiliation and battle dimension
+func (s *Symbol) frame(size float64) frame {
+	u := size / 2
+	affiliation, dimension := s.affiliation(), s.dimension()
+	switch dimension {
+	case 'A':
+		return airFrame(affiliation, u, 1)
+	case 'U':
+		return airFrame(affiliation, u, -1)
+	}
+
+	switch affiliation {
+	case 'F':
+		if dimension == 'S' || s.Function[0] == 'E' {
+			r := 1.2 * u
+			return frame{
+				path: fmt.Sprintf("M%s,0 A%s,%s 0 1,1 %s,0 A%s,%s 0 1,1 %s,0 Z", num(-r), num(r), num(r), num(r), num(r), num(r), num(-r)),
+				top:  -r, bottom: r, halfWidth: r,
+				icon:   box{0, 0, 0.85 * u, 0.85 * u},
+				staffX: -r, staffY: 0,
+			}
+		}
+		return frame{
+			path: fmt.Sprintf("M%s,%s H%s V%s H%s Z", num(-1.5*u), num(-u), num(1.5*u), num(u), num(-1.5*u)),
+			top:  -u, bottom: u, halfWidth: 1.5 * u,
+			icon:   box{0, 0, 1.5 * u, u},
+			staffX: -1.5 * u, staffY: u,
+		}
+	case 'H':
+		d := 1.4 * u
+		return frame{
+			path: fmt.Sprintf("M0,%s L%s,0 L0,%s L%s,0 Z", num(-d), num(d), num(d), num(-d)),
+			top:  -d, bottom: d, halfWidth: d,
+			icon:   box{0, 0, 0.7 * u, 0.7 * u},
+			staffX: -d, staffY: 0,
+		}
+	case 'N':
+		a := 1.1 * u
+		return frame{
+			path: fmt.Sprintf("M%s,%s H%s V%s H%s Z", num(-a), num(-a), num(a), num(a), num(-a)),
+			top:  -a, bottom: a, halfWidth: a,
+			icon:   box{0, 0, a, a},
+			staffX: -a, staffY: a,
+		}
+	}
+	c := 0.6 * u
+	r := num(c) + "," + num(c)
+	return frame{
+		path: fmt.Sprintf("M%s,%s A%s 0 0,1 %s,%s A%s 0 0,1 %s,%s A%s 0 0,1 %s,%s A%s 0 0,1 %s,%s Z",
+			num(-c), num(-c), r, num(c), num(-c), r, num(c), num(c), r, num(-c), num(c), r, num(-c), num(-c)),
+		top: -2 * c, bottom: 2 * c, halfWidth: 2 * c,
+		icon:   box{0, 0, c, c},
+		staffX: -2 * c, staffY: 0,
+	}
+}
+
+// airFrame returns a frame open at the bottom for air symbols, subsurface frames are mirrored vertically
+// with "sign" -1
+func airFrame(affiliation byte, u, sign float64) frame {
+	y := func(value float64) string { return num(value * sign) }
+	sweep := "1"
+	if sign < 0 {
+		sweep = "0"
+	}
+
+	var f frame
+	switch affiliation {
+	case 'F':
+		r := 1.2 * u
+		f = frame{
+			path: fmt.Sprintf("M%s,%s V0 A%s,%s 0 0,%s %s,0 V%s", num(-r), y(0.6*u), num(r), num(r), sweep, num(r), y(0.6*u)),
+			top:  -r, bottom: 0.6 * u, halfWidth: r,
+			icon: box{0, -0.2 * u, 0.8 * u, 0.6 * u},
+		}
+	case 'H':
+		a := 1.1 * u
+		f = frame{
+			path: fmt.Sprintf("M%s,%s V%s L0,%s L%s,%s V%s", num(-a), y(0.6*u), y(-0.3*u), y(-1.3*u), num(a), y(-0.3*u), y(0.6*u)),
+			top:  -1.3 * u, bottom: 0.6 * u, halfWidth: a,
+			icon: box{0, -0.2 * u, 0.7 * u, 0.5 * u},
+		}
+	case 'N':
+		a := 1.1 * u
+		f = frame{
+			path: fmt.Sprintf("M%s,%s V%s H%s V%s", num(-a), y(0.6*u), y(-a), num(a), y(0.6*u)),
+			top:  -a, bottom: 0.6 * u, halfWidth: a,
+			icon: box{0, -0.25 * u, a, 0.85 * u},
+		}
+	default:
+		r := 0.6 * u
+		f = frame{
+			path: fmt.Sprintf("M%s,%s V%s A%s,%s 0 0,%s 0,%s A%s,%s 0 0,%s %s,%s V%s",
+				num(-2*r), y(0.5*u), y(-0.3*u), num(r), num(r), sweep, y(-0.3*u), num(r), num(r), sweep, num(2*r), y(-0.3*u), y(0.5*u)),
+			top: -0.3*u - r, bottom: 0.5 * u, halfWidth: 2 * r,
+			icon: box{0, -0.1 * u, 0.8 * u, 0.5 * u},
+		}
+	}
+	if sign < 0 {
+		f.top, f.bottom = -f.bottom, -f.top
+		f.icon.y = -f.icon.y
+	}
+	f.staffX, f.staffY = -f.halfWidth, f.bottom
+	return f
+}
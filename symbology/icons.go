@@ -0,0 +1,101 @@
+package symbology
+
+import (
+	"fmt"
+	"strings"
+)
+
+// icon draws lines and filled shapes inside the icon area of the frame
+type icon struct {
+	strokes func(b box) []string
+	fills   func(b box) []string
+}
+
+func noPaths(b box) []string { return nil }
+
+// point returns coordinates of the point of the area, "x" and "y" are from -1 to 1
+func (b box) point(x, y float64) string {
+	return num(b.x+x*b.w) + "," + num(b.y+y*b.h)
+}
+
+// path builds a path of points of the area, "M" and "L" commands are added between them
+func (b box) path(points ...float64) string {
+	parts := []string{}
+	for i := 0; i+1 < len(points); i += 2 {
+		command := "L"
+		if i == 0 {
+			command = "M"
+		}
+		parts = append(parts, command+b.point(points[i], points[i+1]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// ellipse returns a path of the ellipse centered in the area with radii relative to its half sizes
+func (b box) ellipse(rx, ry float64) string {
+	x, y := num(rx*b.w), num(ry*b.h)
+	return fmt.Sprintf("M%s A%s,%s 0 1,1 %s A%s,%s 0 1,1 %s Z", b.point(-rx, 0), x, y, b.point(rx, 0), x, y, b.point(-rx, 0))
+}
+
+func strokes(paths func(b box) []string) icon {
+	return icon{strokes: paths, fills: noPaths}
+}
+
+var (
+	infantry       = func(b box) []string { return []string{b.path(-1, -1, 1, 1), b.path(-1, 1, 1, -1)} }
+	armour         = func(b box) []string { return []string{b.ellipse(0.6, 0.5)} }
+	reconnaissance = func(b box) []string { return []string{b.path(-1, 1, 1, -1)} }
+)
+
+// Icons by battle dimension and function ID, the longest matching prefix of the function ID is used
+var icons = map[byte]map[string]icon{
+	'G': {
+		"UCI":  strokes(infantry),
+		"UCIM": strokes(func(b box) []string { return append(infantry(b), armour(b)...) }),
+		"UCA":  strokes(armour),
+		"UCR":  strokes(reconnaissance),
+		"UCF":  {strokes: noPaths, fills: func(b box) []string { return []string{b.ellipse(0.2, 0.3)} }},
+		"UCE": strokes(func(b box) []string {
+			return []string{b.path(-0.5, 0.3, -0.5, -0.3, 0.5, -0.3, 0.5, 0.3), b.path(0, -0.3, 0, 0.3)}
+		}),
+		"UCD": strokes(func(b box) []string {
+			return []string{fmt.Sprintf("M%s Q%s %s", b.point(-1, 1), b.point(0, -0.2), b.point(1, 1))}
+		}),
+		"UCV": strokes(func(b box) []string { return []string{b.path(-0.6, -0.4, 0.6, 0.4, 0.6, -0.4, -0.6, 0.4) + " Z"} }),
+		"UUS": strokes(func(b box) []string { return []string{b.path(-1, -1, -0.15, 0.4, 0.15, -0.4, 1, 1)} }),
+		"USM": strokes(func(b box) []string { return []string{b.path(0, -0.7, 0, 0.7), b.path(-0.5, 0, 0.5, 0)} }),
+		"USS": strokes(func(b box) []string { return []string{b.path(-1, 0.5, 1, 0.5)} }),
+		"UST": strokes(func(b box) []string {
+			return []string{b.ellipse(0.3, 0.45), b.path(0, -0.45, 0, 0.45), b.path(-0.3, 0, 0.3, 0)}
+		}),
+	},
+	'A': {
+		"MF": {strokes: noPaths, fills: func(b box) []string {
+			return []string{b.path(0, -0.9, 0.1, -0.2, 0.7, 0.1, 0.7, 0.3, 0.1, 0.2, 0.07, 0.7, 0.25, 0.9,
+				-0.25, 0.9, -0.07, 0.7, -0.1, 0.2, -0.7, 0.3, -0.7, 0.1, -0.1, -0.2) + " Z"}
+		}},
+		"MH": strokes(func(b box) []string {
+			return []string{b.path(-0.7, -0.5, 0.7, 0.5, 0.7, -0.5, -0.7, 0.5) + " Z", b.path(0, -0.8, 0, 0.8)}
+		}),
+	},
+	'S': {
+		"C": {strokes: noPaths, fills: func(b box) []string {
+			return []string{b.path(-0.8, -0.1, 0.8, -0.1, 0.5, 0.4, -0.5, 0.4) + " Z", b.path(-0.2, -0.1, -0.2, -0.5, 0.2, -0.5, 0.2, -0.1) + " Z"}
+		}},
+	},
+	'U': {
+		"S": {strokes: noPaths, fills: func(b box) []string { return []string{b.ellipse(0.7, 0.25)} }},
+	},
+}
+
+// iconFor returns the icon of the symbol function, symbols with unknown functions have no icon
+func iconFor(s *Symbol) icon {
+	function := strings.TrimRight(s.Function, "-")
+	table := icons[s.dimension()]
+	for length := len(function); length > 0; length-- {
+		if i, ok := table[function[:length]]; ok {
+			return i
+		}
+	}
+	return icon{strokes: noPaths, fills: noPaths}
+}
@@ -0,0 +1,215 @@
+// Package symbology draws military symbols in APP-6 / MIL-STD-2525C style as vector SVG. Symbols are generated
+// from symbol identification codes (SIDC): frame and fill by standard identity and battle dimension, icon by
+// function ID, echelon and headquarters, task force and feint/dummy modifiers, and text amplifiers.
+package symbology
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/TerraFactory/svgo"
+)
+
+// Symbol is a parsed SIDC, e.g. "SFGPUCI----D---": coding scheme, standard identity, battle dimension, status,
+// function ID, symbol modifier (headquarters, task force, feint/dummy) and echelon. Country and order of battle
+// are not drawn. Shorter codes are padded with "-".
+type Symbol struct {
+	SIDC      string
+	Identity  byte
+	Dimension byte
+	Status    byte
+	Function  string
+	Modifier  byte
+	Echelon   byte
+}
+
+// Amplifiers are text fields written to the right of the frame
+type Amplifiers struct {
+	UniqueDesignation string
+	HigherFormation   string
+	AdditionalInfo    string
+}
+
+// Fill colors by affiliation: friend, hostile, neutral and unknown
+var fillColors = map[byte]string{'F': "#80e0ff", 'H': "#ff8080", 'N': "#aaffaa", 'U': "#ffff80"}
+
+// Colors of the operational condition bar: fully capable, damaged, destroyed and full to capacity
+var conditionColors = map[byte]string{'C': "#00c000", 'D': "#ffff00", 'X': "#ff0000", 'F': "#0000ff"}
+
+// Echelons drawn above the frame: dots are drawn as circles, other ones as text
+var echelons = map[byte]string{
+	'A': "Ø", 'B': ".", 'C': "..", 'D': "...", 'E': "I", 'F': "II", 'G': "III",
+	'H': "X", 'I': "XX", 'J': "XXX", 'K': "XXXX", 'L': "XXXXX", 'M': "XXXXXX",
+}
+
+// EchelonMarker returns the marker of the echelon given as a SIDC echelon letter ("F" is "II"),
+// other values are returned as is
+func EchelonMarker(echelon string) string {
+	if len(echelon) == 1 {
+		if marker, ok := echelons[strings.ToUpper(echelon)[0]]; ok {
+			return marker
+		}
+	}
+	return echelon
+}
+
+// Parse reads a SIDC of 10 to 15 characters
+func Parse(sidc string) (*Symbol, error) {
+	code := strings.ToUpper(strings.TrimSpace(sidc))
+	if len(code) < 10 || len(code) > 15 {
+		return nil, fmt.Errorf("SIDC %s should have 10 to 15 characters", sidc)
+	}
+	code += strings.Repeat("-", 15-len(code))
+	if !strings.ContainsRune("SGWIOE", rune(code[0])) {
+		return nil, fmt.Errorf("Unknown coding scheme %c of SIDC %s", code[0], sidc)
+	}
+	if !strings.ContainsRune("PUAFNSHGWDLMJKO", rune(code[1])) {
+		return nil, fmt.Errorf("Unknown standard identity %c of SIDC %s", code[1], sidc)
+	}
+	if !strings.ContainsRune("PAGSUFXZ", rune(code[2])) {
+		return nil, fmt.Errorf("Unknown battle dimension %c of SIDC %s", code[2], sidc)
+	}
+	status := code[3]
+	if status == '-' {
+		status = 'P'
+	}
+	if !strings.ContainsRune("APCDXF", rune(status)) {
+		return nil, fmt.Errorf("Unknown status %c of SIDC %s", code[3], sidc)
+	}
+	return &Symbol{
+		SIDC:      code,
+		Identity:  code[1],
+		Dimension: code[2],
+		Status:    status,
+		Function:  code[4:10],
+		Modifier:  code[10],
+		Echelon:   code[11],
+	}, nil
+}
+
+// IsSIDC returns true if the code can be parsed as a SIDC
+func IsSIDC(code string) bool {
+	_, err := Parse(code)
+	return err == nil
+}
+
+// affiliation returns 'F', 'H', 'N' or 'U' which define the frame shape and the fill
+func (s *Symbol) affiliation() byte {
+	switch s.Identity {
+	case 'F', 'A', 'D', 'M':
+		return 'F'
+	case 'H', 'S', 'J', 'K':
+		return 'H'
+	case 'N', 'L':
+		return 'N'
+	}
+	return 'U'
+}
+
+// dimension returns 'A' (air and space), 'S' (sea surface), 'U' (subsurface) or 'G' (ground and others)
+func (s *Symbol) dimension() byte {
+	switch s.Dimension {
+	case 'A', 'P':
+		return 'A'
+	case 'S', 'U':
+		return s.Dimension
+	}
+	return 'G'
+}
+
+// Size returns width and height of the box centered at the point which contains the frame of the symbol
+func (s *Symbol) Size(size float64) (float64, float64) {
+	f := s.frame(size)
+	return f.halfWidth * 2, math.Max(-f.top, f.bottom) * 2
+}
+
+func num(value float64) string {
+	return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64)
+}
+
+// Render draws the symbol centered at (0, 0). "size" is the height of a friendly ground unit frame.
+func (s *Symbol) Render(canvas *svg.SVG, size float64) {
+	f := s.frame(size)
+	strokeWidth := size / 20
+	style := fmt.Sprintf("fill:%s; stroke:black; stroke-width:%s; stroke-linejoin:round", fillColors[s.affiliation()], num(strokeWidth))
+	if s.Status == 'A' {
+		style += fmt.Sprintf("; stroke-dasharray:%s,%s", num(size/8), num(size/12))
+	}
+	canvas.Path(f.path, style)
+
+	icon := iconFor(s)
+	for _, d := range icon.strokes(f.icon) {
+		canvas.Path(d, fmt.Sprintf("fill:none; stroke:black; stroke-width:%s", num(strokeWidth)))
+	}
+	for _, d := range icon.fills(f.icon) {
+		canvas.Path(d, "fill:black; stroke:none")
+	}
+
+	textStyle := fmt.Sprintf("font-family:sans-serif; font-size:%spx; font-weight:bold; text-anchor:middle", num(size*0.35))
+	switch s.Identity {
+	case 'J', 'K', 'D', 'L', 'M', 'G', 'W':
+		letter := map[byte]string{'J': "J", 'K': "K"}[s.Identity]
+		if letter == "" {
+			letter = "X"
+		}
+		canvas.Text(int(f.halfWidth+size*0.15), int(f.top+size*0.3), letter, textStyle)
+	}
+
+	if color, ok := conditionColors[s.Status]; ok {
+		canvas.Path(fmt.Sprintf("M%s,%s H%s V%s H%s Z", num(-f.halfWidth), num(f.bottom+size*0.05),
+			num(f.halfWidth), num(f.bottom+size*0.2), num(-f.halfWidth)),
+			fmt.Sprintf("fill:%s; stroke:black; stroke-width:%s", color, num(strokeWidth/2)))
+	}
+	s.renderModifiers(canvas, f, size, strokeWidth, textStyle)
+}
+
+// renderModifiers draws echelon, headquarters staff, task force bracket and feint/dummy indicator
+func (s *Symbol) renderModifiers(canvas *svg.SVG, f frame, size, strokeWidth float64, textStyle string) {
+	lineStyle := fmt.Sprintf("fill:none; stroke:black; stroke-width:%s", num(strokeWidth))
+	top := f.top - size*0.08
+	echelon := echelons[s.Echelon]
+	switch {
+	case echelon == "":
+	case strings.Trim(echelon, ".") == "":
+		count := len(echelon)
+		for i := 0; i < count; i++ {
+			x := (float64(i) - float64(count-1)/2) * size * 0.2
+			canvas.Circle(int(x), int(top-size*0.08), int(math.Max(size*0.06, 1)), "fill:black")
+		}
+	default:
+		canvas.Text(0, int(top), echelon, textStyle)
+	}
+
+	headquarters := strings.IndexByte("ABCD", s.Modifier) >= 0
+	taskForce := strings.IndexByte("BDEG", s.Modifier) >= 0
+	feint := strings.IndexByte("CDFG", s.Modifier) >= 0
+	if headquarters {
+		canvas.Path(fmt.Sprintf("M%s,%s V%s", num(f.staffX), num(f.staffY), num(f.bottom+size*0.6)), lineStyle)
+	}
+	if taskForce {
+		width := math.Max(float64(len(echelon)), 2) * size * 0.14
+		canvas.Path(fmt.Sprintf("M%s,%s V%s H%s V%s", num(-width), num(f.top), num(top-size*0.35),
+			num(width), num(f.top)), lineStyle)
+	}
+	if feint {
+		y := top - size*0.45
+		canvas.Path(fmt.Sprintf("M%s,%s L0,%s L%s,%s", num(-f.halfWidth), num(f.top), num(y), num(f.halfWidth), num(f.top)),
+			lineStyle+fmt.Sprintf("; stroke-dasharray:%s", num(size/10)))
+	}
+}
+
+// RenderAmplifiers writes text amplifiers to the right of the symbol centered at (x, y): higher formation,
+// unique designation and additional information from top to bottom
+func (s *Symbol) RenderAmplifiers(canvas *svg.SVG, x, y, size float64, amplifiers Amplifiers, fontFamily string) {
+	f := s.frame(size)
+	left := x + f.halfWidth + size*0.15
+	style := fmt.Sprintf("font-family:%s; font-size:%spx; fill:black; text-anchor:start", fontFamily, num(size*0.3))
+	rows := []string{amplifiers.HigherFormation, amplifiers.UniqueDesignation, amplifiers.AdditionalInfo}
+	for i, text := range rows {
+		if text != "" {
+			canvas.Text(int(left), int(y+(float64(i)-1)*size*0.35+size*0.1), text, style)
+		}
+	}
+}
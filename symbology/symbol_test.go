@@ -0,0 +1,94 @@
+package symbology
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/TerraFactory/svgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	symbol, err := Parse("sfgpucim--af")
+	assert.Nil(t, err)
+	assert.Equal(t, "SFGPUCIM--AF---", symbol.SIDC)
+	assert.Equal(t, byte('F'), symbol.affiliation())
+	assert.Equal(t, "UCIM--", symbol.Function)
+	assert.Equal(t, byte('A'), symbol.Modifier)
+	assert.Equal(t, byte('F'), symbol.Echelon)
+
+	_, err = Parse("SFG")
+	assert.NotNil(t, err)
+	_, err = Parse("SQGPUCI----")
+	assert.NotNil(t, err)
+	assert.False(t, IsSIDC("1000000101"))
+}
+
+func TestSymbol_Size(t *testing.T) {
+	friend, _ := Parse("SFGPUCI----")
+	w, h := friend.Size(30)
+	assert.Equal(t, 45.0, w)
+	assert.Equal(t, 30.0, h)
+
+	hostile, _ := Parse("SHGPUCI----")
+	w, h = hostile.Size(30)
+	assert.InDelta(t, 42.0, w, 0.001)
+	assert.InDelta(t, 42.0, h, 0.001)
+}
+
+func TestSymbol_Render(t *testing.T) {
+	for _, sidc := range []string{"SFGPUCIM--AF---", "SHGAUCR----D", "SNSPC------", "SUUPS------", "SFAPMF-----", "SHAPMH----E", "SUGPUCA---BI"} {
+		symbol, err := Parse(sidc)
+		assert.Nil(t, err)
+		var buffer bytes.Buffer
+		canvas := svg.New(&buffer)
+		canvas.Start(100, 100)
+		symbol.Render(canvas, 30)
+		symbol.RenderAmplifiers(canvas, 0, 0, 30, Amplifiers{UniqueDesignation: "1/2", HigherFormation: "34 MR"}, "sans-serif")
+		canvas.End()
+
+		decoder := xml.NewDecoder(&buffer)
+		for {
+			_, err := decoder.Token()
+			if err == io.EOF {
+				break
+			}
+			assert.Nil(t, err, sidc)
+			if err != nil {
+				break
+			}
+		}
+	}
+
+	planned, _ := Parse("SHGAUCR----D")
+	var buffer bytes.Buffer
+	planned.Render(svg.New(&buffer), 30)
+	assert.Contains(t, buffer.String(), "fill:#ff8080")
+	assert.Contains(t, buffer.String(), "stroke-dasharray")
+	assert.Equal(t, 3, strings.Count(buffer.String(), "<circle"))
+}
+
+func TestCodes_Lookup(t *testing.T) {
+	codes := Codes{"1000000101": "SFGPUCIM---F---"}
+
+	sidc, ok := codes.Lookup("1000000101")
+	assert.True(t, ok)
+	assert.Equal(t, "SFGPUCIM---F---", sidc)
+	sidc, ok = codes.Lookup("SHGPUCA----")
+	assert.True(t, ok)
+	assert.Equal(t, "SHGPUCA----", sidc)
+	_, ok = codes.Lookup("1000000002")
+	assert.False(t, ok)
+	_, ok = Codes(nil).Lookup("1000000101")
+	assert.False(t, ok)
+}
+
+func TestEchelonMarker(t *testing.T) {
+	assert.Equal(t, "II", EchelonMarker("F"))
+	assert.Equal(t, "XX", EchelonMarker("i"))
+	assert.Equal(t, "XXX", EchelonMarker("XXX"))
+	assert.Equal(t, "", EchelonMarker(""))
+}
@@ -0,0 +1,126 @@
+package tiles
+
+import (
+	"math"
+)
+
+// axisOfAdvance is an outline of an axis of advance along a line: two sides of a corridor which narrows
+// from the tail to a third of its width, and an arrowhead at the end of the line
+type axisOfAdvance struct {
+	leftXs, leftYs   []int
+	rightXs, rightYs []int
+	headXs, headYs   []int
+}
+
+type axisPoint struct {
+	x, y float64
+}
+
+// newAxisOfAdvance returns the outline of the axis along the smoothed line of "xs", "ys" with the corridor
+// "width" pixels wide at the tail. Width of the arrowhead is the same. It returns nil for a line without length.
+func newAxisOfAdvance(xs, ys []int, width float64) *axisOfAdvance {
+	points := []axisPoint{}
+	for i := range xs {
+		point := axisPoint{float64(xs[i]), float64(ys[i])}
+		if len(points) == 0 || point != points[len(points)-1] {
+			points = append(points, point)
+		}
+	}
+	if len(points) < 2 {
+		return nil
+	}
+	distances := []float64{0}
+	for i := 1; i < len(points); i++ {
+		distances = append(distances, distances[i-1]+math.Hypot(points[i].x-points[i-1].x, points[i].y-points[i-1].y))
+	}
+	length := distances[len(distances)-1]
+
+	/* The corridor ends at the base of the arrowhead, the head is not longer than a half of the line */
+	headLength := math.Min(math.Sqrt(3)/2*width, length/2)
+	tip := points[len(points)-1]
+	last := len(points) - 1
+	for last > 0 && distances[last-1] >= length-headLength {
+		last--
+	}
+	ratio := (length - headLength - distances[last-1]) / (distances[last] - distances[last-1])
+	base := axisPoint{
+		points[last-1].x + (points[last].x-points[last-1].x)*ratio,
+		points[last-1].y + (points[last].y-points[last-1].y)*ratio,
+	}
+	line := append(append([]axisPoint{}, points[:last]...), base)
+	lineLength := length - headLength
+
+	axis := axisOfAdvance{}
+	for i, point := range line {
+		normal := axisNormal(line, i)
+		halfWidth := width / 2
+		if lineLength > 0 {
+			halfWidth *= 1 - 2.0/3.0*math.Min(distances[i], lineLength)/lineLength
+		}
+		if i == len(line)-1 {
+			halfWidth = width / 6
+		}
+		axis.leftXs = append(axis.leftXs, round(point.x+normal.x*halfWidth))
+		axis.leftYs = append(axis.leftYs, round(point.y+normal.y*halfWidth))
+		axis.rightXs = append(axis.rightXs, round(point.x-normal.x*halfWidth))
+		axis.rightYs = append(axis.rightYs, round(point.y-normal.y*halfWidth))
+	}
+
+	normal := axisNormal([]axisPoint{base, tip}, 0)
+	axis.headXs = []int{round(base.x + normal.x*width/2), round(tip.x), round(base.x - normal.x*width/2)}
+	axis.headYs = []int{round(base.y + normal.y*width/2), round(tip.y), round(base.y - normal.y*width/2)}
+	return &axis
+}
+
+// outline returns the contour of the axis from the tail of the left side around the arrowhead
+// to the tail of the right side
+func (axis *axisOfAdvance) outline() ([]int, []int) {
+	xs := append(append([]int{}, axis.leftXs...), axis.headXs...)
+	ys := append(append([]int{}, axis.leftYs...), axis.headYs...)
+	for i := len(axis.rightXs) - 1; i >= 0; i-- {
+		xs = append(xs, axis.rightXs[i])
+		ys = append(ys, axis.rightYs[i])
+	}
+	return xs, ys
+}
+
+// axisNormal returns the unit normal of the line at its point. Normals of inner points are bisectors of
+// adjacent segments, lengthened so that sides keep their width on bends.
+func axisNormal(line []axisPoint, i int) axisPoint {
+	direction := func(a, b axisPoint) axisPoint {
+		d := math.Hypot(b.x-a.x, b.y-a.y)
+		if d == 0 {
+			return axisPoint{}
+		}
+		return axisPoint{(b.x - a.x) / d, (b.y - a.y) / d}
+	}
+	var in, out axisPoint
+	if i > 0 {
+		in = direction(line[i-1], line[i])
+	}
+	if i < len(line)-1 {
+		out = direction(line[i], line[i+1])
+	}
+	if i == 0 {
+		in = out
+	} else if i == len(line)-1 {
+		out = in
+	}
+	sum := axisPoint{in.x + out.x, in.y + out.y}
+	d := math.Hypot(sum.x, sum.y)
+	if d < 1e-9 {
+		return axisPoint{-in.y, in.x}
+	}
+	normal := axisPoint{-sum.y / d, sum.x / d}
+	/* Miter length is limited on sharp bends */
+	if cos := normal.x*(-in.y) + normal.y*in.x; cos > 0.5 {
+		normal = axisPoint{normal.x / cos, normal.y / cos}
+	} else {
+		normal = axisPoint{normal.x * 2, normal.y * 2}
+	}
+	return normal
+}
+
+func round(value float64) int {
+	return int(math.Floor(value + 0.5))
+}
@@ -0,0 +1,29 @@
+package tiles
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAxisOfAdvance(t *testing.T) {
+	axis := newAxisOfAdvance([]int{0, 100}, []int{0, 0}, 20)
+	assert.Equal(t, []int{0, 83}, axis.leftXs)
+	assert.Equal(t, []int{10, 3}, axis.leftYs)
+	assert.Equal(t, []int{-10, -3}, axis.rightYs)
+	assert.Equal(t, []int{83, 100, 83}, axis.headXs)
+	assert.Equal(t, []int{10, 0, -10}, axis.headYs)
+
+	xs, ys := axis.outline()
+	assert.Equal(t, []int{0, 83, 83, 100, 83, 83, 0}, xs)
+	assert.Equal(t, []int{10, 3, 10, 0, -10, -3, -10}, ys)
+
+	/* Sides follow all segments of the line, the arrowhead points along the last one */
+	axis = newAxisOfAdvance([]int{0, 100, 100}, []int{0, 0, 100}, 20)
+	assert.Equal(t, 3, len(axis.leftXs))
+	assert.Equal(t, []int{90, 100, 110}, axis.headXs)
+	assert.Equal(t, 100, axis.headYs[1])
+	assert.Equal(t, 83, axis.headYs[0])
+
+	assert.Nil(t, newAxisOfAdvance([]int{5, 5}, []int{5, 5}, 20))
+}
@@ -0,0 +1,269 @@
+package tiles
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/TerraFactory/svgo"
+	"github.com/TerraFactory/tilegenerator/database/entities"
+	"github.com/TerraFactory/tilegenerator/settings/styling/primitives"
+	"github.com/pelletier/go-toml"
+)
+
+// Graphic draws a tactical graphic of the object with parameters of the rule which matched its code
+type Graphic func(r *Renderer, canvas *svg.SVG, object *entities.MapObject, tile *Tile, params GraphicParams) error
+
+// GraphicParams are parameters of a graphic set in its rule
+type GraphicParams map[string]interface{}
+
+// Bool returns the boolean parameter or "def" if it is not set
+func (params GraphicParams) Bool(name string, def bool) bool {
+	if value, ok := params[name].(bool); ok {
+		return value
+	}
+	return def
+}
+
+// Float returns the number parameter or "def" if it is not set
+func (params GraphicParams) Float(name string, def float64) float64 {
+	switch value := params[name].(type) {
+	case float64:
+		return value
+	case int64:
+		return float64(value)
+	case int:
+		return float64(value)
+	}
+	return def
+}
+
+// String returns the text parameter or "def" if it is not set
+func (params GraphicParams) String(name string, def string) string {
+	if value, ok := params[name].(string); ok {
+		return value
+	}
+	return def
+}
+
+// Text returns the text parameter, it is a template expanded with fields of the object (see primitives.ExpandTemplate)
+func (params GraphicParams) Text(name, def string, object *entities.MapObject, zoom int) string {
+	return strings.TrimSpace(primitives.ExpandTemplate(params.String(name, def), object, zoom, primitives.EscapeText))
+}
+
+// Width returns width of the graphic in pixels: Width in pixels or WidthMeters in meters on the ground
+// at the start of the object line, so the width is the same on all tiles. It returns zero if none of them is set.
+func (params GraphicParams) Width(object *entities.MapObject, tile *Tile) float64 {
+	if meters := params.Float("WidthMeters", 0); meters > 0 {
+		lat := (tile.BoundingBox.North + tile.BoundingBox.South) / 2
+		if line, err := object.Geometry.AsLineString(); err == nil && len(line.Coordinates) > 0 {
+			lat = tile.TileY2Lat(line.Coordinates[0].Y)
+		}
+		return meters / tile.MetersPerPixelAt(lat)
+	}
+	return params.Float("Width", 0)
+}
+
+// graphics are tactical graphics by names used in rules, see graphicParams for their parameters
+var graphics = map[string]Graphic{
+	"route": func(r *Renderer, canvas *svg.SVG, object *entities.MapObject, tile *Tile, params GraphicParams) error {
+		return r.RenderRouteAviationFlight(canvas, object, tile, params.Bool("Symbol", true))
+	},
+	"patrol-area": func(r *Renderer, canvas *svg.SVG, object *entities.MapObject, tile *Tile, params GraphicParams) error {
+		return r.RenderPatrollingArea(canvas, object, tile, params.Bool("Symbol", true))
+	},
+	"main-attack": func(r *Renderer, canvas *svg.SVG, object *entities.MapObject, tile *Tile, params GraphicParams) error {
+		return r.RenderAttackMainDirection(canvas, object, tile, params.Width(object, tile))
+	},
+	"planned-main-attack": func(r *Renderer, canvas *svg.SVG, object *entities.MapObject, tile *Tile, params GraphicParams) error {
+		return r.RenderPlannedAttackMainDirection(canvas, object, tile, params.Width(object, tile))
+	},
+	"completed-action": func(r *Renderer, canvas *svg.SVG, object *entities.MapObject, tile *Tile, params GraphicParams) error {
+		return r.RenderCompletedProvideAction(canvas, object, tile, params.Width(object, tile))
+	},
+	"pit": func(r *Renderer, canvas *svg.SVG, object *entities.MapObject, tile *Tile, params GraphicParams) error {
+		return r.RenderPit(canvas, object, tile)
+	},
+	"phase-line": func(r *Renderer, canvas *svg.SVG, object *entities.MapObject, tile *Tile, params GraphicParams) error {
+		return r.RenderPhaseLine(canvas, object, tile,
+			params.Text("Label", "PL ${label}", object, tile.Z),
+			params.Text("Secondary", "${prop.secondary}", object, tile.Z),
+			params.Float("Interval", defaultLabelInterval))
+	},
+	"boundary": func(r *Renderer, canvas *svg.SVG, object *entities.MapObject, tile *Tile, params GraphicParams) error {
+		return r.RenderBoundary(canvas, object, tile,
+			params.Text("Label", "${label}", object, tile.Z),
+			params.Text("Secondary", "${prop.secondary}", object, tile.Z),
+			params.Text("Echelon", "${prop.echelon}", object, tile.Z),
+			params.Float("Interval", defaultLabelInterval))
+	},
+}
+
+// graphicParams are parameters of graphics by names with their kinds: "bool", "number" or "text" (a template).
+// Symbol = false hides the symbol in the middle of routes and patrol areas, Width or WidthMeters set width of axes of advance.
+// Labels of control lines are repeated every Interval pixels, Label of phase lines is "PL ${label}" by default.
+// Echelon of boundaries is a SIDC echelon letter or a marker like "XX".
+var graphicParams = map[string]map[string]string{
+	"route":               {"Symbol": "bool"},
+	"patrol-area":         {"Symbol": "bool"},
+	"main-attack":         {"Width": "number", "WidthMeters": "number"},
+	"planned-main-attack": {"Width": "number", "WidthMeters": "number"},
+	"completed-action":    {"Width": "number", "WidthMeters": "number"},
+	"pit":                 {},
+	"phase-line":          {"Label": "text", "Secondary": "text", "Interval": "number"},
+	"boundary":            {"Label": "text", "Secondary": "text", "Echelon": "text", "Interval": "number"},
+}
+
+// checkParam returns an error if the graphic has no such parameter or the value is not of its kind
+func checkParam(graphic, name string, value interface{}) error {
+	kind, ok := graphicParams[graphic][name]
+	if !ok {
+		return fmt.Errorf("Unknown parameter %s of graphic %s", name, graphic)
+	}
+	switch value.(type) {
+	case bool:
+		ok = kind == "bool"
+	case float64, int64, int:
+		ok = kind == "number"
+	case string:
+		ok = kind == "text"
+	default:
+		ok = false
+	}
+	if !ok {
+		return fmt.Errorf("%s of graphic %s should be a %s, got %v", name, graphic, kind, value)
+	}
+	if text, isText := value.(string); isText {
+		if err := primitives.CheckTemplate(text); err != nil {
+			return fmt.Errorf("%s of graphic %s: %v", name, graphic, err)
+		}
+	}
+	return nil
+}
+
+// defaultLabelInterval is the distance in pixels between labels repeated along control lines
+const defaultLabelInterval = 300
+
+// GraphicRule maps object codes to a graphic. Codes ending with "*" are matched as prefixes.
+type GraphicRule struct {
+	Codes   []string
+	Graphic string
+	Params  GraphicParams
+}
+
+// Render draws the graphic of the rule
+func (rule *GraphicRule) Render(r *Renderer, canvas *svg.SVG, object *entities.MapObject, tile *Tile) error {
+	return graphics[rule.Graphic](r, canvas, object, tile, rule.Params)
+}
+
+// Graphics chooses tactical graphics of objects by their codes.
+// Exact codes are matched first, then prefixes in order of rules.
+type Graphics struct {
+	exact    map[string]*GraphicRule
+	prefixes []string
+	rules    []*GraphicRule
+}
+
+// NewGraphics creates a registry of the rules. Graphics of all rules and their parameters should be known.
+func NewGraphics(rules []GraphicRule) (*Graphics, error) {
+	registry := Graphics{exact: map[string]*GraphicRule{}}
+	for i := range rules {
+		rule := &rules[i]
+		if _, ok := graphics[rule.Graphic]; !ok {
+			return nil, fmt.Errorf("Unknown graphic %s", rule.Graphic)
+		}
+		for name, value := range rule.Params {
+			if err := checkParam(rule.Graphic, name, value); err != nil {
+				return nil, err
+			}
+		}
+		for _, code := range rule.Codes {
+			if strings.HasSuffix(code, "*") {
+				registry.prefixes = append(registry.prefixes, strings.TrimSuffix(code, "*"))
+				registry.rules = append(registry.rules, rule)
+			} else if _, ok := registry.exact[code]; !ok {
+				registry.exact[code] = rule
+			}
+		}
+	}
+	return &registry, nil
+}
+
+// Match returns the rule of the code, or nil if the object is not a tactical graphic
+func (registry *Graphics) Match(code string) *GraphicRule {
+	if rule, ok := registry.exact[code]; ok {
+		return rule
+	}
+	for i, prefix := range registry.prefixes {
+		if strings.HasPrefix(code, prefix) {
+			return registry.rules[i]
+		}
+	}
+	return nil
+}
+
+// LoadGraphics reads rules from [[graphics]] tables of a TOML file:
+//
+//	[[graphics]]
+//	  Codes = ["1000000004", "1210620*"]
+//	  Graphic = "route"
+//	  Symbol = false
+//
+// Keys other than Codes and Graphic are parameters of the graphic, unknown ones are errors.
+func LoadGraphics(path string) (*Graphics, error) {
+	tree, err := toml.LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tables, _ := tree.Get("graphics").([]*toml.TomlTree)
+	rules := []GraphicRule{}
+	for i, table := range tables {
+		rule := GraphicRule{Params: GraphicParams{}}
+		for _, key := range table.Keys() {
+			switch key {
+			case "Graphic":
+				rule.Graphic, _ = table.Get(key).(string)
+			case "Codes":
+				codes, _ := table.Get(key).([]interface{})
+				for _, code := range codes {
+					if s, ok := code.(string); ok {
+						rule.Codes = append(rule.Codes, s)
+					} else {
+						return nil, fmt.Errorf("%s: codes of graphic %v should be strings", path, i+1)
+					}
+				}
+			default:
+				rule.Params[key] = table.Get(key)
+			}
+		}
+		if len(rule.Codes) == 0 {
+			return nil, fmt.Errorf("%s: graphic %v has no Codes", path, i+1)
+		}
+		rules = append(rules, rule)
+	}
+	registry, err := NewGraphics(rules)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return registry, nil
+}
+
+// LoadGraphicsFile reads rules from the file, or returns DefaultGraphics if it doesn't exist
+func LoadGraphicsFile(path string) (*Graphics, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return DefaultGraphics, nil
+	}
+	return LoadGraphics(path)
+}
+
+// DefaultGraphics are used if the styles directory has no graphics file
+var DefaultGraphics, _ = NewGraphics([]GraphicRule{
+	{Codes: []string{"1000000004"}, Graphic: "route", Params: GraphicParams{"Symbol": false}},
+	{Codes: routeAviationsFlightCodes, Graphic: "route"},
+	{Codes: []string{"1000000002"}, Graphic: "patrol-area", Params: GraphicParams{"Symbol": false}},
+	{Codes: patrollingAreaCodes, Graphic: "patrol-area"},
+	{Codes: []string{plannedAttackMainDirectionCode}, Graphic: "planned-main-attack"},
+	{Codes: []string{attackMainDirectionCode}, Graphic: "main-attack"},
+	{Codes: []string{completedProvideActionCode}, Graphic: "completed-action"},
+	{Codes: []string{pitCode}, Graphic: "pit"},
+})
@@ -0,0 +1,106 @@
+package tiles
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/TerraFactory/tilegenerator/database/entities"
+	"github.com/TerraFactory/wktparser/geometry"
+	"github.com/stretchr/testify/assert"
+)
+
+// testLine is a line geometry of test objects
+type testLine struct {
+	geometry.Geometry
+	coords []geometry.Coord
+}
+
+func (l testLine) GetType() int {
+	return geometry.TLineString
+}
+
+func (l testLine) AsLineString() (*geometry.LineString, error) {
+	return &geometry.LineString{Coordinates: l.coords}, nil
+}
+
+func TestDefaultGraphics(t *testing.T) {
+	rule := DefaultGraphics.Match("1000000004")
+	assert.Equal(t, "route", rule.Graphic)
+	assert.False(t, rule.Params.Bool("Symbol", true))
+
+	rule = DefaultGraphics.Match("121062001002010601010100000100")
+	assert.Equal(t, "route", rule.Graphic)
+	assert.True(t, rule.Params.Bool("Symbol", true))
+
+	assert.Equal(t, "pit", DefaultGraphics.Match(pitCode).Graphic)
+	assert.Nil(t, DefaultGraphics.Match("1"))
+}
+
+func TestLoadGraphics(t *testing.T) {
+	file, err := ioutil.TempFile("", "graphics")
+	assert.Nil(t, err)
+	defer os.Remove(file.Name())
+	file.WriteString(`
+[[graphics]]
+  Codes = ["555*"]
+  Graphic = "patrol-area"
+  Symbol = false
+[[graphics]]
+  Codes = ["5551", "777"]
+  Graphic = "main-attack"
+`)
+	file.Close()
+
+	graphics, err := LoadGraphics(file.Name())
+	assert.Nil(t, err)
+	assert.Equal(t, "main-attack", graphics.Match("5551").Graphic)
+	assert.Equal(t, "patrol-area", graphics.Match("5552").Graphic)
+	assert.False(t, graphics.Match("5552").Params.Bool("Symbol", true))
+	assert.Nil(t, graphics.Match(pitCode))
+
+	ioutil.WriteFile(file.Name(), []byte("[[graphics]]\n  Codes = [\"1\"]\n  Graphic = \"circle\"\n"), 0644)
+	_, err = LoadGraphics(file.Name())
+	assert.EqualError(t, err, file.Name()+": Unknown graphic circle")
+
+	graphics, err = LoadGraphicsFile(file.Name() + ".missing")
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultGraphics, graphics)
+}
+
+func TestNewGraphics_Templates(t *testing.T) {
+	_, err := NewGraphics([]GraphicRule{{Codes: []string{"1"}, Graphic: "phase-line", Params: GraphicParams{"Label": "LOA ${label}"}}})
+	assert.Nil(t, err)
+
+	_, err = NewGraphics([]GraphicRule{{Codes: []string{"1"}, Graphic: "boundary", Params: GraphicParams{"Secondary": "${unknown}"}}})
+	assert.EqualError(t, err, "Secondary of graphic boundary: Unknown template variable ${unknown}")
+}
+
+func TestNewGraphics_Params(t *testing.T) {
+	_, err := NewGraphics([]GraphicRule{{Codes: []string{"1"}, Graphic: "route", Params: GraphicParams{"Width": 10}}})
+	assert.EqualError(t, err, "Unknown parameter Width of graphic route")
+
+	_, err = NewGraphics([]GraphicRule{{Codes: []string{"1"}, Graphic: "patrol-area", Params: GraphicParams{"Symbol": "no"}}})
+	assert.EqualError(t, err, "Symbol of graphic patrol-area should be a bool, got no")
+
+	_, err = NewGraphics([]GraphicRule{{Codes: []string{"1"}, Graphic: "main-attack", Params: GraphicParams{"WidthMeters": int64(500)}}})
+	assert.Nil(t, err)
+}
+
+func TestGraphicParams_Width(t *testing.T) {
+	params := GraphicParams{"WidthMeters": 1000}
+	lats, lons := []float64{60.1, 55.2}, []float64{30.1, 30.3}
+	widths := []float64{}
+	for _, tile := range []*Tile{NewTile(Lon2Tile(30.1, 10), Lat2Tile(60.1, 10), 10), NewTile(Lon2Tile(30.3, 10), Lat2Tile(55.2, 10), 10)} {
+		coords := []geometry.Coord{}
+		for i := range lats {
+			x, y := tile.Degrees2Pixels(lats[i], lons[i])
+			coords = append(coords, geometry.Coord{X: float64(x), Y: float64(y)})
+		}
+		widths = append(widths, params.Width(&entities.MapObject{Geometry: testLine{coords: coords}}, tile))
+	}
+	assert.InDelta(t, widths[0], widths[1], 0.01, "width should not depend on the tile")
+	assert.InDelta(t, 1000/NewTile(0, 0, 10).MetersPerPixelAt(60.1), widths[0], 0.01)
+
+	assert.Equal(t, 12.0, GraphicParams{"Width": 12}.Width(&entities.MapObject{}, NewTile(0, 0, 1)))
+}
@@ -0,0 +1,106 @@
+package tiles
+
+import (
+	"log"
+	"sync/atomic"
+
+	"github.com/TerraFactory/tilegenerator/assets"
+	"github.com/TerraFactory/tilegenerator/settings"
+	"github.com/TerraFactory/tilegenerator/settings/styling"
+	"github.com/TerraFactory/tilegenerator/symbology"
+)
+
+// ImageFetcher requests images by URL, e.g. *assets.Fetcher
+type ImageFetcher interface {
+	Fetch(url string) ([]byte, error)
+}
+
+// Logger writes problems of rendering, e.g. *log.Logger
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+type standardLogger struct{}
+
+func (standardLogger) Printf(format string, v ...interface{}) {
+	log.Printf(format, v...)
+}
+
+// StandardLogger writes to the standard logger of the "log" package
+var StandardLogger Logger = standardLogger{}
+
+// RendererOptions are dependencies of a renderer.
+// Styles returns the current style set, it is called once for each tile, so styles can be reloaded while tiles are rendered.
+// Stores resolve "asset://" hrefs and Codes map object codes to SIDC. DefaultGraphics are used if Graphics is nil,
+// assets.DefaultFetcher if Fetcher is nil, assets.DefaultStores if Stores is nil and StandardLogger if Logger is nil.
+type RendererOptions struct {
+	Styles   func() *map[string]styling.Style
+	Graphics *Graphics
+	Fetcher  ImageFetcher
+	Stores   *assets.Stores
+	Codes    symbology.Codes
+	Logger   Logger
+}
+
+// Renderer draws tiles with its own settings, styles, images, symbols and log,
+// so renderers of different configurations can be used in one process.
+type Renderer struct {
+	settings *settings.Settings
+	styles   func() *map[string]styling.Style
+	fetcher  ImageFetcher
+	stores   *assets.Stores
+	codes    symbology.Codes
+	logger   Logger
+	graphics atomic.Value
+}
+
+// NewRenderer creates a renderer of the configuration, "logging.debug" adds reasons of failures to tiles.
+// Default settings are used if "conf" is nil and no styles are used if options have no Styles.
+func NewRenderer(conf *settings.Settings, options RendererOptions) *Renderer {
+	if conf == nil {
+		conf = &settings.Settings{}
+	}
+	r := Renderer{
+		settings: conf,
+		styles:   options.Styles,
+		fetcher:  options.Fetcher,
+		stores:   options.Stores,
+		codes:    options.Codes,
+		logger:   options.Logger,
+	}
+	r.SetGraphics(options.Graphics)
+	if r.styles == nil {
+		r.styles = func() *map[string]styling.Style { return &map[string]styling.Style{} }
+	}
+	if r.fetcher == nil {
+		r.fetcher = assets.DefaultFetcher
+	}
+	if r.stores == nil {
+		r.stores = assets.DefaultStores
+	}
+	if r.logger == nil {
+		r.logger = StandardLogger
+	}
+	return &r
+}
+
+// Graphics returns the current registry of tactical graphics
+func (r *Renderer) Graphics() *Graphics {
+	graphics, _ := r.graphics.Load().(*Graphics)
+	return graphics
+}
+
+// SetGraphics replaces the registry of tactical graphics, DefaultGraphics are used if it is nil.
+// Tiles being rendered keep the previous registry.
+func (r *Renderer) SetGraphics(graphics *Graphics) {
+	if graphics == nil {
+		graphics = DefaultGraphics
+	}
+	r.graphics.Store(graphics)
+}
+
+// loadImage returns content of an image of IMAGE primitives, URLs are requested by the fetcher of the renderer
+// and "asset://" hrefs are symbols of its stores
+func (r *Renderer) loadImage(href string) ([]byte, error) {
+	return r.stores.LoadWith(r.fetcher.Fetch, href)
+}
@@ -0,0 +1,144 @@
+package tiles
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TerraFactory/svgo"
+	"github.com/TerraFactory/tilegenerator/assets"
+	"github.com/TerraFactory/tilegenerator/database/entities"
+	"github.com/TerraFactory/tilegenerator/settings"
+	"github.com/TerraFactory/tilegenerator/settings/styling"
+	"github.com/TerraFactory/tilegenerator/settings/styling/primitives"
+	"github.com/TerraFactory/wktparser/geometry"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeFetcher map[string][]byte
+
+func (fetcher fakeFetcher) Fetch(url string) ([]byte, error) {
+	if data, ok := fetcher[url]; ok {
+		return data, nil
+	}
+	return nil, errors.New("HTTP 404")
+}
+
+type fakeLogger struct {
+	lines []string
+}
+
+func (logger *fakeLogger) Printf(format string, v ...interface{}) {
+	logger.lines = append(logger.lines, fmt.Sprintf(format, v...))
+}
+
+func newTestRenderer(fetcher fakeFetcher, logger *fakeLogger) *Renderer {
+	styles := func() *map[string]styling.Style {
+		return &map[string]styling.Style{}
+	}
+	return NewRenderer(&settings.Settings{UrlAPI: "http://api"}, RendererOptions{Styles: styles, Fetcher: fetcher, Logger: logger})
+}
+
+func TestRenderer_RenderTile(t *testing.T) {
+	var result bytes.Buffer
+	used := newTestRenderer(fakeFetcher{}, &fakeLogger{}).RenderTile(NewTile(0, 0, 1), &[]entities.MapObject{}, &result)
+	assert.Empty(t, used)
+	assert.Contains(t, result.String(), "<svg")
+	assert.True(t, strings.HasSuffix(strings.TrimSpace(result.String()), "</svg>"))
+}
+
+func TestNewRenderer_Defaults(t *testing.T) {
+	renderer := NewRenderer(nil, RendererOptions{})
+	assert.Equal(t, assets.DefaultFetcher, renderer.fetcher)
+	assert.Equal(t, DefaultGraphics, renderer.Graphics())
+
+	var result bytes.Buffer
+	used := renderer.RenderTile(NewTile(0, 0, 1), &[]entities.MapObject{{ID: 1, Geometry: testPoint{}}}, &result)
+	assert.Empty(t, used)
+	assert.Contains(t, result.String(), "<svg")
+}
+
+func TestRenderer_RenderImageOnLine(t *testing.T) {
+	logger := &fakeLogger{}
+	renderer := newTestRenderer(fakeFetcher{"http://api/api/maps/object/7/png": []byte("png")}, logger)
+
+	var result bytes.Buffer
+	renderer.renderImageOnLine(svg.New(&result), &entities.MapObject{ID: 7, Scale: 1}, 90, 10, 20, 10)
+	assert.Contains(t, result.String(), "data:image/png;base64,cG5n")
+	assert.Empty(t, logger.lines)
+
+	result.Reset()
+	renderer.renderImageOnLine(svg.New(&result), &entities.MapObject{ID: 8, Scale: 1}, 90, 10, 20, 10)
+	assert.Equal(t, "", result.String())
+	assert.Equal(t, []string{"Can't render image of object 8: HTTP 404 \n"}, logger.lines)
+}
+
+// testPoint is a point geometry of test objects
+type testPoint struct {
+	geometry.Geometry
+	x, y float64
+}
+
+func (p testPoint) GetType() int {
+	return geometry.TPoint
+}
+
+func (p testPoint) AsPoint() (*geometry.Point, error) {
+	return &geometry.Point{Coordinates: geometry.Coord{X: p.x, Y: p.y}}, nil
+}
+
+func (p testPoint) ConvertCoords(f func(float64, float64) (float64, float64)) {}
+
+func newTestStores(t *testing.T, dir, icon string) *assets.Stores {
+	path := filepath.Join(dir, icon)
+	assert.Nil(t, os.MkdirAll(path, 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(path, "icon.svg"), []byte("<svg>"+icon+"</svg>"), 0644))
+	stores := assets.NewStores()
+	assert.Nil(t, stores.LoadAll(map[string]string{"mil": path}))
+	return stores
+}
+
+func TestRenderer_Isolation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stores")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	image, err := primitives.NewImagePrimitive(&map[string]interface{}{"Href": "asset://mil/icon", "Width": 10.0, "Height": 10.0})
+	assert.Nil(t, err)
+	symbol, err := primitives.NewMilitarySymbolPrimitive(&map[string]interface{}{})
+	assert.Nil(t, err)
+	styles := func() *map[string]styling.Style {
+		return &map[string]styling.Style{"home": {GeometryType: geometry.TPoint, Name: "home", Primitives: []styling.Primitive{image, symbol}}}
+	}
+	render := func(options RendererOptions, debug bool) string {
+		options.Styles = styles
+		options.Fetcher = fakeFetcher{}
+		var result bytes.Buffer
+		object := entities.MapObject{ID: 5, StyleName: "home", Code: "1000000101", Scale: 1, Geometry: testPoint{x: 10, y: 10}}
+		NewRenderer(&settings.Settings{Debug: debug}, options).RenderTile(NewTile(0, 0, 1), &[]entities.MapObject{object}, &result)
+		return result.String()
+	}
+
+	first, second := &fakeLogger{}, &fakeLogger{}
+	tile := render(RendererOptions{Stores: newTestStores(t, dir, "first"), Logger: first,
+		Codes: map[string]string{"1000000101": "SFGPUCIM---F---"}}, false)
+	assert.Contains(t, tile, base64.StdEncoding.EncodeToString([]byte("<svg>first</svg>")))
+	assert.Contains(t, tile, "<use", "the symbol of the mapped code is drawn")
+	assert.NotContains(t, tile, "<!-- Can't")
+	assert.Empty(t, first.lines)
+
+	tile = render(RendererOptions{Stores: newTestStores(t, dir, "second"), Logger: second}, true)
+	assert.Contains(t, tile, base64.StdEncoding.EncodeToString([]byte("<svg>second</svg>")))
+	assert.Equal(t, []string{"Can't render SYMBOL for object 5: Code 1000000101 is not a SIDC and is not mapped to it \n"}, second.lines)
+	assert.Empty(t, first.lines)
+
+	tile = render(RendererOptions{Stores: assets.NewStores(), Logger: second}, true)
+	assert.Contains(t, tile, "<!-- Can't render IMAGE asset://mil/icon for object 5: Asset store mil is not found -->",
+		"reasons of failures are written into tiles of debug renderers")
+}
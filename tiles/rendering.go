@@ -1,6 +1,7 @@
 package tiles
 
 import (
+	"bytes"
 	"encoding/base64"
 	"fmt"
 	"io"
@@ -9,9 +10,9 @@ import (
 
 	"github.com/TerraFactory/svgo"
 	"github.com/TerraFactory/tilegenerator/database/entities"
-	"github.com/TerraFactory/tilegenerator/settings"
 	"github.com/TerraFactory/tilegenerator/settings/styling"
-	"github.com/TerraFactory/tilegenerator/utils"
+	"github.com/TerraFactory/tilegenerator/settings/styling/primitives"
+	"github.com/TerraFactory/tilegenerator/symbology"
 	"github.com/TerraFactory/wktparser/geometry"
 )
 
@@ -50,95 +51,72 @@ var pitCode = "21056441215588"
 
 var hashTypes map[int]string
 
-// RenderTile takes a tile struct, map objects and then draws these objects on the tile
-func RenderTile(tile *Tile, objects *[]entities.MapObject, styles *map[string]styling.Style, writer io.Writer) {
+// Size of symbols drawn in the middle of routes and patrolling areas depending on zoom
+var lineSymbolWidth = primitives.ZoomValue{{Zoom: 0, Value: 5}, {Zoom: 22, Value: 115}}
+var lineSymbolHeight = primitives.ZoomValue{{Zoom: 0, Value: 7}, {Zoom: 22, Value: 139}}
+
+// RenderTile takes a tile struct, map objects and then draws these objects on the tile.
+// It returns names of styles used by the objects.
+func (r *Renderer) RenderTile(tile *Tile, objects *[]entities.MapObject, writer io.Writer) []string {
 	f := func(x, y float64) (float64, float64) {
 		nx, ny := tile.Degrees2Pixels(y, x)
 		return float64(nx), float64(ny)
 	}
 
-	canvas := svg.New(writer)
-	canvas.Start(TileSize, TileSize)
+	/* Styles and graphics may be reloaded concurrently, the same set is used for the whole tile */
+	sortedStyles := styling.SortStyles(r.styles())
+	graphics := r.Graphics()
+	usedStyles := []string{}
+	used := map[string]bool{}
+	/* Objects are rendered into the buffer, images and shapes repeated in them are written into <defs> before it */
+	var body bytes.Buffer
+	defs := primitives.NewDefs(&body)
+	defs.Load = r.loadImage
+	defs.Codes = r.codes
+	defs.Debug = r.settings.Debug
+	defs.Logger = r.logger
+	canvas := svg.New(defs)
 	for _, object := range *objects {
 		object.Geometry.ConvertCoords(f)
 
-		for _, style := range *styles {
+		if object.IsTrack {
+			r.RenderTrack(canvas, &object)
+			continue
+		}
 
-			if style.ShouldRender(&object) {
-				style.Render(&object, canvas)
+		if style := styling.MatchStyle(sortedStyles, &object); style != nil {
+			style.Render(&object, canvas, tile.Z)
+			if !used[style.Name] {
+				used[style.Name] = true
+				usedStyles = append(usedStyles, style.Name)
+			}
 
-				if object.IsAntenna && object.NeedShowDirectionalDiagram {
-					RenderBeamDiagram(canvas, &object, tile)
-				}
+			if object.IsAntenna && object.NeedShowDirectionalDiagram {
+				r.RenderBeamDiagram(canvas, &object, tile)
+			}
 
-				if object.NeedShowAzimuthalGrid {
-					RenderAzimuthalGrid(canvas, &object, tile)
-				}
+			if object.NeedShowAzimuthalGrid {
+				r.RenderAzimuthalGrid(canvas, &object, tile)
 			}
 		}
 
-		if contains(object.Code, patrollingAreaCodes) {
-			RenderPatrollingArea(canvas, &object, tile)
-		} else if contains(object.Code, routeAviationsFlightCodes) {
-			RenderRouteAviationFlight(canvas, &object, tile)
-		} else if object.Code == plannedAttackMainDirectionCode {
-			RenderPlannedAttackMainDirection(canvas, &object, tile)
-		} else if object.Code == attackMainDirectionCode {
-			RenderAttackMainDirection(canvas, &object, tile)
-		} else if object.Code == completedProvideActionCode {
-			RenderCompletedProvideAction(canvas, &object, tile)
-		} else if object.Code == pitCode {
-			RenderPit(canvas, &object, tile)
+		if rule := graphics.Match(object.Code); rule != nil {
+			rule.Render(r, canvas, &object, tile)
 		}
 	}
 
-	canvas.End()
+	result := svg.New(writer)
+	result.Start(TileSize, TileSize)
+	defs.Render(result)
+	writer.Write(body.Bytes())
+	result.End()
+	return usedStyles
 }
 
 type chartPoint struct {
 	x, y, z, value float64
 }
 
-type bigArrow struct {
-	pointXs, pointYs, arrowXs, arrowYs []int
-	centerX, centerY                   int
-
-	rotateAngel float64
-}
-
-func newBigArrow(tile *Tile, coords []geometry.Coord) *bigArrow {
-	x1, y1 := int(coords[0].X), int(coords[0].Y)
-	x2, y2 := int(coords[1].X), int(coords[1].Y)
-
-	centerX, centerY := getLineCenter(x1, y1, x2, y2)
-	distance := distanceBeetweenPoints(x1, y1, x2, y2)
-	sizeEdgeArrow := int(distance / 10)
-
-	line1X1, line1Y1 := centerX+int(distance/2), centerY+sizeEdgeArrow/2/3
-	line1X2, line1Y2 := centerX-int(distance/2), centerY+sizeEdgeArrow/2
-
-	line2X1, line2Y1 := centerX+int(distance/2), centerY-sizeEdgeArrow/2/3
-	line2X2, line2Y2 := centerX-int(distance/2), centerY-sizeEdgeArrow/2
-
-	rightLinePointX, rightLinePointY := centerX+int(distance/2), centerY
-
-	arrowXs := []int{rightLinePointX, rightLinePointX + int(math.Sqrt(3)/2.0*float64(sizeEdgeArrow)), rightLinePointX}
-	arrowYs := []int{rightLinePointY + sizeEdgeArrow/2, rightLinePointY, rightLinePointY - sizeEdgeArrow/2}
-
-	pointXs := []int{line1X2, line1X1, arrowXs[0], arrowXs[1], arrowXs[2], line2X1, line2X2}
-	pointYs := []int{line1Y2, line1Y1, arrowYs[0], arrowYs[1], arrowYs[2], line2Y1, line2Y2}
-
-	return &bigArrow{
-		centerX:     centerX,
-		centerY:     centerY,
-		pointXs:     pointXs,
-		pointYs:     pointYs,
-		arrowXs:     arrowXs,
-		arrowYs:     arrowYs,
-		rotateAngel: getAngel(x1, y1, x2, y2) + 180,
-	}
-}
-
 func newCharPoint(x, y, z, value float64) *chartPoint {
 	var cp chartPoint
 	cp.x = x
@@ -276,7 +254,7 @@ func getMax(chartPoints []*chartPoint) float64 {
 	return max
 }
 
-func RenderPit(canvas *svg.SVG, object *entities.MapObject, tile *Tile) error {
+func (r *Renderer) RenderPit(canvas *svg.SVG, object *entities.MapObject, tile *Tile) error {
 	line, err := object.Geometry.AsLineString()
 	if err != nil {
 		return err
@@ -362,73 +340,76 @@ func drawHatchingOnLine(canvas *svg.SVG, beginX, beginY, endX, endY int, style s
 	}
 }
 
-func RenderAttackMainDirection(canvas *svg.SVG, object *entities.MapObject, tile *Tile) error {
-	if object.ColorInner == "" {
-		object.ColorInner = "red"
-	}
-
-	if object.ColorOuter == "" {
-		object.ColorOuter = "red"
-	}
-
+// RenderAttackMainDirection renders an axis of advance along the line, "width" is its width in pixels
+// at the tail, or a tenth of the line length if it is zero
+func (r *Renderer) RenderAttackMainDirection(canvas *svg.SVG, object *entities.MapObject, tile *Tile, width float64) error {
+	setAxisColor(object)
 	weight := 1
 
 	styleLine := fmt.Sprintf("stroke:%v; stroke-width: %v; fill: none;", object.ColorOuter, weight)
 	styleArrow := fmt.Sprintf("stroke:%v; stroke-width: %v; fill: %v;", object.ColorOuter, weight, object.ColorInner)
 
-	return renderBigArrow(canvas, object, tile, styleLine, styleArrow)
+	return renderAxisOfAdvance(canvas, object, width, styleLine, styleArrow)
 }
 
-func RenderPlannedAttackMainDirection(canvas *svg.SVG, object *entities.MapObject, tile *Tile) error {
-	if object.ColorInner == "" {
-		object.ColorInner = "red"
-	}
-
-	if object.ColorOuter == "" {
-		object.ColorOuter = "red"
-	}
-
+// RenderPlannedAttackMainDirection renders a planned axis of advance with dashed outline
+func (r *Renderer) RenderPlannedAttackMainDirection(canvas *svg.SVG, object *entities.MapObject, tile *Tile, width float64) error {
+	setAxisColor(object)
 	weight := 1
 
-	styleLine := fmt.Sprintf("stroke:%v; stroke-width: %v; fill: none;stroke-dasharray: 10;", object.ColorOuter, weight)
-	styleArrow := fmt.Sprintf("stroke:%v; stroke-width: %v; fill: %v;", object.ColorOuter, weight, object.ColorInner)
+	styleLine := fmt.Sprintf("stroke:%v; stroke-width: %v; fill: none; stroke-dasharray: 10;", object.ColorOuter, weight)
+	styleArrow := fmt.Sprintf("stroke:%v; stroke-width: %v; fill: %v; stroke-dasharray: 10;", object.ColorOuter, weight, object.ColorInner)
 
-	return renderBigArrow(canvas, object, tile, styleLine, styleArrow)
+	return renderAxisOfAdvance(canvas, object, width, styleLine, styleArrow)
 }
 
-func RenderCompletedProvideAction(canvas *svg.SVG, object *entities.MapObject, tile *Tile) error {
+// RenderCompletedProvideAction renders a completed axis of advance with unfilled arrowhead
+func (r *Renderer) RenderCompletedProvideAction(canvas *svg.SVG, object *entities.MapObject, tile *Tile, width float64) error {
+	setAxisColor(object)
 	weight := 1
-	if object.ColorOuter != "" {
-		object.ColorOuter = "red"
-	}
 
 	style := fmt.Sprintf("stroke:%v; stroke-width: %v; fill: none;", object.ColorOuter, weight)
 
-	return renderBigArrow(canvas, object, tile, style, style)
+	return renderAxisOfAdvance(canvas, object, width, style, style)
+}
+
+func setAxisColor(object *entities.MapObject) {
+	if object.ColorInner == "" {
+		object.ColorInner = "red"
+	}
+
+	if object.ColorOuter == "" {
+		object.ColorOuter = "red"
+	}
 }
 
-func renderBigArrow(canvas *svg.SVG, object *entities.MapObject, tile *Tile, styleLine, styleArrow string) error {
+// renderAxisOfAdvance draws the corridor along the smoothed line with "styleLine" and the arrowhead with "styleArrow".
+func renderAxisOfAdvance(canvas *svg.SVG, object *entities.MapObject, width float64, styleLine, styleArrow string) error {
 	line, err := object.Geometry.AsLineString()
 	if err != nil {
 		return err
 	}
 
-	direction := newBigArrow(tile, line.Coordinates)
-
-	transformationStyle := fmt.Sprintf("rotate(%v,%v,%v)", direction.rotateAngel, direction.centerX, direction.centerY)
-
-	canvas.Gtransform(transformationStyle)
-
-	canvas.Polyline(direction.arrowXs, direction.arrowYs, styleArrow)
-	canvas.Polyline(direction.pointXs, direction.pointYs, styleLine)
+	xs, ys := polylineToCurvePoints(coordToXsYs(line.Coordinates))
+	if width <= 0 {
+		width = getLengthPolyline(xs, ys) / 10
+	}
+	axis := newAxisOfAdvance(xs, ys, width)
+	if axis == nil {
+		return nil
+	}
 
+	canvas.Group("id=\"id" + strconv.Itoa(object.ID) + "\"")
+	canvas.Polygon(axis.headXs, axis.headYs, styleArrow)
+	outlineXs, outlineYs := axis.outline()
+	canvas.Polyline(outlineXs, outlineYs, styleLine)
 	canvas.Gend()
 
 	return nil
 }
 
-// RenderRouteAviationFlight renders an aviation route on a tile
-func RenderRouteAviationFlight(canvas *svg.SVG, object *entities.MapObject, tile *Tile) error {
+// RenderRouteAviationFlight renders an aviation route on a tile, "symbol" draws the symbol of the object in the middle
+func (r *Renderer) RenderRouteAviationFlight(canvas *svg.SVG, object *entities.MapObject, tile *Tile, symbol bool) error {
 	line, err := object.Geometry.AsLineString()
 	if err != nil {
 		return err
@@ -443,18 +424,22 @@ func RenderRouteAviationFlight(canvas *svg.SVG, object *entities.MapObject, tile
 	// renderPolyline(canvas, coords, style)
 	renderCurve(canvas, coords, style)
 
-	if object.Code != "1000000004" {
+	if symbol {
 		xs, ys := coordToXsYs(coords)
 		xs, ys = polylineToCurvePoints(xs, ys)
 		x, y, angel := getCenterPolylineAndAngel(xs, ys)
-		renderImageOnLine(canvas, object.Scale, angel, x, y, object.ID, tile.Z)
+		r.renderImageOnLine(canvas, object, angel, x, y, tile.Z)
 	}
 
 	if object.Label != "" {
 		xs, ys := coordToXsYs(coords)
 		xs, ys = polylineToCurvePoints(xs, ys)
-		x, y, _ := getCenterPolylineAndAngel(xs, ys)
-		renderTextOnLine(canvas, x, y, object.Label, object.Position)
+		x, y, angel := getCenterPolylineAndAngel(xs, ys)
+		var symbolWidth, symbolHeight float64
+		if symbol {
+			symbolWidth, symbolHeight = lineSymbolBox(object, angel, tile.Z)
+		}
+		renderTextOnLine(canvas, x, y, symbolWidth, symbolHeight, object.Label, object.Position, tile.Z)
 	}
 
 	renderArrowRouteAviationFlight(coords, canvas, object, tile)
@@ -463,6 +448,150 @@ func RenderRouteAviationFlight(canvas *svg.SVG, object *entities.MapObject, tile
 	return nil
 }
 
+// RenderPhaseLine renders a control line with "label" at both ends and every "interval" pixels, "secondary" text is under it
+func (r *Renderer) RenderPhaseLine(canvas *svg.SVG, object *entities.MapObject, tile *Tile, label, secondary string, interval float64) error {
+	line, err := object.Geometry.AsLineString()
+	if err != nil {
+		return err
+	}
+	setDefaultColor(object)
+	xs, ys := coordToXsYs(line.Coordinates)
+	if len(xs) < 2 {
+		return nil
+	}
+
+	canvas.Group("id=\"id" + strconv.Itoa(object.ID) + "\"")
+	renderPolyline(canvas, line.Coordinates, fmt.Sprintf("stroke: %v; stroke-width: %v; fill: none;", object.ColorOuter, 2))
+
+	if label != "" {
+		count := len(xs)
+		startAngel := pointsAngel(xs[1], ys[1], xs[0], ys[0])
+		endAngel := pointsAngel(xs[count-2], ys[count-2], xs[count-1], ys[count-1])
+		renderTextAtLineEnd(canvas, float64(xs[0]), float64(ys[0]), startAngel, label, object.ColorOuter)
+		renderTextAtLineEnd(canvas, float64(xs[count-1]), float64(ys[count-1]), endAngel, label, object.ColorOuter)
+	}
+	for _, distance := range labelDistances(getLengthPolyline(xs, ys), interval) {
+		x, y, angel := pointOnPolyline(xs, ys, distance)
+		renderTextAlongLine(canvas, x, y, angel, -4, label, "middle", object.ColorOuter)
+		renderTextAlongLine(canvas, x, y, angel, 14, secondary, "middle", object.ColorOuter)
+	}
+
+	canvas.Gend()
+	return nil
+}
+
+// RenderBoundary renders a boundary between units with "echelon", "label" and "secondary" markers every "interval" pixels
+func (r *Renderer) RenderBoundary(canvas *svg.SVG, object *entities.MapObject, tile *Tile, label, secondary, echelon string, interval float64) error {
+	line, err := object.Geometry.AsLineString()
+	if err != nil {
+		return err
+	}
+	setDefaultColor(object)
+	xs, ys := coordToXsYs(line.Coordinates)
+	if len(xs) < 2 {
+		return nil
+	}
+
+	canvas.Group("id=\"id" + strconv.Itoa(object.ID) + "\"")
+	renderPolyline(canvas, line.Coordinates, fmt.Sprintf("stroke: %v; stroke-width: %v; fill: none;", object.ColorOuter, 2))
+
+	marker := symbology.EchelonMarker(echelon)
+	for _, distance := range labelDistances(getLengthPolyline(xs, ys), interval) {
+		x, y, angel := pointOnPolyline(xs, ys, distance)
+		renderTextAlongLine(canvas, x, y, angel, 4, marker, "middle", object.ColorOuter)
+		renderTextAlongLine(canvas, x, y, angel, -10, label, "middle", object.ColorOuter)
+		renderTextAlongLine(canvas, x, y, angel, 18, secondary, "middle", object.ColorOuter)
+	}
+
+	canvas.Gend()
+	return nil
+}
+
+// labelDistances returns distances from the start of a line of "length" pixels to labels repeated every "interval" pixels.
+// A short line has one label in the middle.
+func labelDistances(length, interval float64) []float64 {
+	if interval <= 0 || length < interval {
+		return []float64{length / 2}
+	}
+	distances := []float64{}
+	for distance := interval / 2; distance < length; distance += interval {
+		distances = append(distances, distance)
+	}
+	return distances
+}
+
+// pointOnPolyline returns the point at "distance" pixels from the start of the polyline and the angel
+// of its segment to the axis Ox in degrees
+func pointOnPolyline(xs, ys []int, distance float64) (float64, float64, float64) {
+	for i := 1; i < len(xs); i++ {
+		length := distanceBeetweenPoints(xs[i-1], ys[i-1], xs[i], ys[i])
+		if (distance <= length || i == len(xs)-1) && length > 0 {
+			ratio := math.Min(distance/length, 1)
+			x := float64(xs[i-1]) + float64(xs[i]-xs[i-1])*ratio
+			y := float64(ys[i-1]) + float64(ys[i]-ys[i-1])*ratio
+			return x, y, pointsAngel(xs[i-1], ys[i-1], xs[i], ys[i])
+		}
+		distance -= length
+	}
+	return float64(xs[0]), float64(ys[0]), 0
+}
+
+// pointsAngel returns the angel of the direction from the first point to the second one to the axis Ox in degrees
+func pointsAngel(x1, y1, x2, y2 int) float64 {
+	return math.Atan2(float64(y2-y1), float64(x2-x1)) * 180 / math.Pi
+}
+
+// readableAngel turns the angel of text by 180 degrees if the text would be upside down
+func readableAngel(angel float64) (float64, bool) {
+	if angel > 90 {
+		return angel - 180, true
+	}
+	if angel < -90 {
+		return angel + 180, true
+	}
+	return angel, false
+}
+
+// renderTextAlongLine writes the text rotated along the line at (x, y), "dy" moves it across the line
+func renderTextAlongLine(canvas *svg.SVG, x, y, angel, dy float64, text, anchor, color string) {
+	if text == "" {
+		return
+	}
+	angel, _ = readableAngel(angel)
+	canvas.Text(0, int(dy), text,
+		fmt.Sprintf("transform=\"translate(%v,%v) rotate(%v)\"", int(x), int(y), angel),
+		fmt.Sprintf("font-family:sans-serif; font-size:12px; fill:%v; text-anchor:%s; stroke:white; stroke-width:3; stroke-linejoin:round; paint-order:stroke;", color, anchor))
+}
+
+// renderTextAtLineEnd writes the text after the end (x, y) of the line going in the direction of "angel"
+func renderTextAtLineEnd(canvas *svg.SVG, x, y, angel float64, text, color string) {
+	radians := angel * math.Pi / 180
+	x, y = x+4*math.Cos(radians), y+4*math.Sin(radians)
+	anchor := "start"
+	if _, turned := readableAngel(angel); turned {
+		anchor = "end"
+	}
+	renderTextAlongLine(canvas, x, y, angel, 4, text, anchor, color)
+}
+
+// RenderTrack renders a track of the object: a line through its former positions with a dot at each of them
+func (r *Renderer) RenderTrack(canvas *svg.SVG, object *entities.MapObject) error {
+	line, err := object.Geometry.AsLineString()
+	if err != nil {
+		return err
+	}
+	setDefaultColor(object)
+
+	canvas.Group("id=\"track" + strconv.Itoa(object.ID) + "\"")
+	renderPolyline(canvas, line.Coordinates, fmt.Sprintf("stroke: %v; stroke-width: %v; stroke-opacity: 0.6; fill: none;", object.ColorOuter, 2))
+	for _, coord := range line.Coordinates {
+		canvas.Circle(int(coord.X), int(coord.Y), 2, fmt.Sprintf("fill: %v; fill-opacity: 0.6;", object.ColorOuter))
+	}
+	canvas.Gend()
+
+	return nil
+}
+
 func renderPolyline(canvas *svg.SVG, coords []geometry.Coord, style string) {
 	xs, ys := coordToXsYs(coords)
 
@@ -506,7 +635,7 @@ func renderArrowRouteAviationFlight(coords []geometry.Coord, canvas *svg.SVG, ob
 /*
 RenderPatrollingArea (район барражирования)
 */
-func RenderPatrollingArea(canvas *svg.SVG, object *entities.MapObject, tile *Tile) error {
+func (r *Renderer) RenderPatrollingArea(canvas *svg.SVG, object *entities.MapObject, tile *Tile, symbol bool) error {
 	line, err := object.Geometry.AsLineString()
 	if err != nil {
 		return err
@@ -521,14 +650,18 @@ func RenderPatrollingArea(canvas *svg.SVG, object *entities.MapObject, tile *Til
 	// renderPolyline(canvas, coords, fmt.Sprintf("stroke: %v; fill: none;", object.ColorOuter))
 	renderCurve(canvas, coords, fmt.Sprintf("stroke: %v; fill: none;", object.ColorOuter))
 
-	if object.Code != "1000000002" {
+	if symbol {
 		curveXs, curveYs := polylineToCurvePoints(xs, ys)
 		x, y, angel := getCenterPolylineAndAngel(curveXs, curveYs)
-		renderImageOnLine(canvas, object.Scale, angel, x, y, object.ID, tile.Z)
+		r.renderImageOnLine(canvas, object, angel, x, y, tile.Z)
 	}
 	if object.Label != "" {
-		x, y, _ := getCenterPolylineAndAngel(xs, ys)
-		renderTextOnLine(canvas, x, y, object.Label, object.Position)
+		x, y, angel := getCenterPolylineAndAngel(xs, ys)
+		var symbolWidth, symbolHeight float64
+		if symbol {
+			symbolWidth, symbolHeight = lineSymbolBox(object, angel, tile.Z)
+		}
+		renderTextOnLine(canvas, x, y, symbolWidth, symbolHeight, object.Label, object.Position, tile.Z)
 	}
 
 	renderRightPartPatrollingArea(canvas, xs[0], ys[0], xs[1], ys[1], object.ColorInner, object.ColorOuter)
@@ -600,55 +733,69 @@ func setDefaultColor(object *entities.MapObject) {
 	}
 }
 
-//this function is used temporary, till we don't use styles for rendering of primitives
-func renderImageOnLine(canvas *svg.SVG, scale, angel float64, x, y, id, zoom int) {
-	pathConfig := "./config.toml"
-	settings, err := settings.GetSettings(&pathConfig)
-
-	if err == nil {
-		href := fmt.Sprintf("%v/api/maps/object/%v/png", settings.UrlAPI, id)
-		if result, err := utils.GetImgByURL(href); err == nil {
-			imgBase64Str := base64.StdEncoding.EncodeToString(result)
-
-			img2html := "data:image/png;base64," + imgBase64Str
-
-			imageWidth := scale * float64(5+5*zoom)
-			imageHeight := scale * float64(7+6*zoom)
-
-			canvas.Image(x-(int)(imageWidth/2.0),
-				y-(int)(imageHeight/2.0),
-				(int)(imageWidth),
-				(int)(imageHeight),
-				img2html,
-				fmt.Sprintf("transform=\"rotate(%v,%v,%v)\"", angel-90, x, y))
-		}
+// lineSymbol returns the military symbol of the line object: SIDC of its "sidc" property or its code mapped to a SIDC
+func (r *Renderer) lineSymbol(object *entities.MapObject) (*symbology.Symbol, bool) {
+	sidc, ok := object.Properties.String("sidc")
+	if !ok {
+		sidc, ok = r.codes.Lookup(object.Code)
+	}
+	if !ok {
+		return nil, false
 	}
+	symbol, err := symbology.Parse(sidc)
+	return symbol, err == nil
 }
 
 //this function is used temporary, till we don't use styles for rendering of primitives
-func renderTextOnLine(svg *svg.SVG, x, y int, label, position string) {
-	var xShift, yShift int
+// Symbols with a SIDC are generated, images of other ones are requested from the API.
+func (r *Renderer) renderImageOnLine(canvas *svg.SVG, object *entities.MapObject, angel float64, x, y, zoom int) {
+	scale, id := object.Scale, object.ID
+	if symbol, ok := r.lineSymbol(object); ok {
+		// The frame of a friendly unit is 1.5 times wider than high, it fills the box of the image
+		primitives.RenderSymbol(canvas, symbol, float64(x), float64(y), scale*lineSymbolWidth.At(zoom)/1.5, angel-90)
+		return
+	}
 
-	if position == "" {
-		position = "bottom"
+	href := fmt.Sprintf("%v/api/maps/object/%v/png", r.settings.UrlAPI, id)
+	result, err := r.fetcher.Fetch(href)
+	if err != nil {
+		r.logger.Printf("Can't render image of object %v: %v \n", id, err)
+		return
 	}
+	imgBase64Str := base64.StdEncoding.EncodeToString(result)
+
+	img2html := "data:image/png;base64," + imgBase64Str
+
+	imageWidth := scale * lineSymbolWidth.At(zoom)
+	imageHeight := scale * lineSymbolHeight.At(zoom)
+
+	canvas.Image(x-(int)(imageWidth/2.0),
+		y-(int)(imageHeight/2.0),
+		(int)(imageWidth),
+		(int)(imageHeight),
+		img2html,
+		fmt.Sprintf("transform=\"rotate(%v,%v,%v)\"", angel-90, x, y))
+}
+
+// lineLabel draws labels of lines with default typography
+var lineLabel, _ = primitives.NewTextPrimitive(&map[string]interface{}{})
 
-	switch position {
-	case "top":
-		xShift = -20
-		yShift = -40
-	case "bottom":
-		xShift = -20
-		yShift = 40
-	case "left":
-		xShift = -60
-		yShift = 10
-	case "right":
-		xShift = 20
-		yShift = 10
+//this function is used temporary, till we don't use styles for rendering of primitives
+// Label is placed around the symbol on the line, "symbolWidth" and "symbolHeight" are zero if there is no symbol.
+func renderTextOnLine(svg *svg.SVG, x, y int, symbolWidth, symbolHeight float64, label, position string, zoom int) {
+	if position == "" {
+		position = "bottom"
 	}
+	lineLabel.RenderAt(svg, float64(x), float64(y), symbolWidth, symbolHeight, label, position, zoom)
+}
 
-	svg.Text(int(x+xShift), int(y+yShift), label)
+// lineSymbolBox returns size of the box around the symbol rendered by renderImageOnLine
+func lineSymbolBox(object *entities.MapObject, angel float64, zoom int) (float64, float64) {
+	width := object.Scale * lineSymbolWidth.At(zoom)
+	height := object.Scale * lineSymbolHeight.At(zoom)
+	radians := (angel - 90) * math.Pi / 180
+	sin, cos := math.Abs(math.Sin(radians)), math.Abs(math.Cos(radians))
+	return width*cos + height*sin, width*sin + height*cos
 }
 
 func getLengthPolyline(xs, ys []int) float64 {
@@ -698,7 +845,7 @@ RenderBeamDiagram - drawing satellite directional diagram
  "q" - угол
  "n" - sidelobes(боковые лепестки)
 */
-func RenderBeamDiagram(canvas *svg.SVG, object *entities.MapObject, tile *Tile) error {
+func (r *Renderer) RenderBeamDiagram(canvas *svg.SVG, object *entities.MapObject, tile *Tile) error {
 	point, err := object.Geometry.AsPoint()
 	if err != nil {
 		return err
@@ -722,7 +869,7 @@ func RenderBeamDiagram(canvas *svg.SVG, object *entities.MapObject, tile *Tile)
 }
 
 //RenderAzimuthalGrid ...
-func RenderAzimuthalGrid(canvas *svg.SVG, object *entities.MapObject, tile *Tile) error {
+func (r *Renderer) RenderAzimuthalGrid(canvas *svg.SVG, object *entities.MapObject, tile *Tile) error {
 	point, err := object.Geometry.AsPoint()
 	if err != nil {
 		return err
@@ -780,15 +927,6 @@ func getStrokeWidthAzimuthalGrid(radius float64) float64 {
 	return float64(radius) / float64(100)
 }
 
-func contains(sample string, list []string) bool {
-	for _, b := range list {
-		if b == sample {
-			return true
-		}
-	}
-	return false
-}
-
 func renderCurve(canvas *svg.SVG, coords []geometry.Coord, style string) {
 	xs, ys := coordToXsYs(coords)
 	percentLength := 0.5
@@ -0,0 +1,47 @@
+package tiles
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/TerraFactory/svgo"
+	"github.com/TerraFactory/tilegenerator/database/entities"
+	"github.com/TerraFactory/wktparser/geometry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabelDistances(t *testing.T) {
+	assert.Equal(t, []float64{50}, labelDistances(100, 300))
+	assert.Equal(t, []float64{150, 450, 750}, labelDistances(800, 300))
+}
+
+func TestPointOnPolyline(t *testing.T) {
+	x, y, angel := pointOnPolyline([]int{0, 100, 100}, []int{0, 0, 100}, 150)
+	assert.Equal(t, 100.0, x)
+	assert.Equal(t, 50.0, y)
+	assert.Equal(t, 90.0, angel)
+
+	x, y, angel = pointOnPolyline([]int{0, 100}, []int{0, 0}, 30)
+	assert.Equal(t, 30.0, x)
+	assert.Equal(t, 0.0, y)
+	assert.Equal(t, 0.0, angel)
+}
+
+func TestRenderTextAtLineEnd(t *testing.T) {
+	var result bytes.Buffer
+	canvas := svg.New(&result)
+	/* The line goes to the left, the text is turned to be readable and ends at the line end */
+	renderTextAtLineEnd(canvas, 10, 20, 180, "PL Red", "black")
+	assert.Contains(t, result.String(), `transform="translate(6,20) rotate(0)"`)
+	assert.Contains(t, result.String(), "text-anchor:end")
+	assert.Contains(t, result.String(), ">PL Red</text>")
+}
+
+func TestRenderPhaseLine_ShortLine(t *testing.T) {
+	var result bytes.Buffer
+	renderer := newTestRenderer(fakeFetcher{}, &fakeLogger{})
+	object := &entities.MapObject{Geometry: testLine{coords: []geometry.Coord{{X: 10, Y: 10}}}}
+	assert.Nil(t, renderer.RenderPhaseLine(svg.New(&result), object, NewTile(0, 0, 1), "PL Red", "", 300))
+	assert.Nil(t, renderer.RenderBoundary(svg.New(&result), object, NewTile(0, 0, 1), "", "", "XX", 300))
+	assert.Equal(t, "", result.String())
+}
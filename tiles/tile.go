@@ -7,6 +7,9 @@ import (
 // TileSize is a size of each tile in pixels
 const TileSize = 256
 
+// MaxLatitude is the most north latitude of the Web Mercator projection
+const MaxLatitude = 85.05112877980659
+
 // Tile contains tile properties
 // Z,X,Y - tile coordinates according to OSM specs(see http://wiki.openstreetmap.org/wiki/Slippy_map_tilenames)
 // Bounding box - geographical coordinates of each side of tile
@@ -62,3 +65,85 @@ func (tile *Tile) Degrees2Pixels(lat, lon float64) (x int, y int) {
 func (tile *Tile) Contains(lat, lon float64) bool {
 	return tile.BoundingBox.Contains(lat, lon)
 }
+
+// PixelSize returns the size of one tile pixel in degrees of longitude at the tile zoom.
+func (tile *Tile) PixelSize() float64 {
+	return 360.0 / (TileSize * math.Exp2(float64(tile.Z)))
+}
+
+// MinPixelSize returns the smallest size of one tile pixel in degrees of latitude or longitude.
+// Pixels of Web Mercator are cos(latitude) times shorter in latitude than in longitude, so the latitude
+// of the tile edge farther from the equator is used and a tolerance in such pixels is not visible on the whole tile.
+func (tile *Tile) MinPixelSize() float64 {
+	lat := math.Min(math.Max(math.Abs(tile.BoundingBox.North), math.Abs(tile.BoundingBox.South)), MaxLatitude)
+	return tile.PixelSize() * math.Cos(lat*math.Pi/180)
+}
+
+// EarthCircumference is the length of the equator in meters of the Web Mercator projection
+const EarthCircumference = 40075016.686
+
+// MetersPerPixel returns the size of one tile pixel in meters on the ground at the middle of the tile
+func (tile *Tile) MetersPerPixel() float64 {
+	return tile.MetersPerPixelAt((tile.BoundingBox.North + tile.BoundingBox.South) / 2)
+}
+
+// MetersPerPixelAt returns the size of one pixel of the tile zoom in meters on the ground at the latitude
+func (tile *Tile) MetersPerPixelAt(lat float64) float64 {
+	return EarthCircumference * math.Cos(lat*math.Pi/180) / (TileSize * math.Exp2(float64(tile.Z)))
+}
+
+// TileY2Lat converts a tile Y coordinate in pixels into latitude, it is the inverse of Lat2TileY
+func (tile *Tile) TileY2Lat(y float64) float64 {
+	n := math.Pi * (1 - 2*(float64(tile.Y)+y/TileSize)/math.Exp2(float64(tile.Z)))
+	return math.Atan(math.Sinh(n)) * 180 / math.Pi
+}
+
+// Lon2Tile returns X coordinate of the tile which contains the longitude on the zoom level
+func Lon2Tile(lon float64, z int) int {
+	return clampTileCoordinate(int(math.Floor((lon+180.0)/360.0*math.Exp2(float64(z)))), z)
+}
+
+// Lat2Tile returns Y coordinate of the tile which contains the latitude on the zoom level
+func Lat2Tile(lat float64, z int) int {
+	lat = math.Max(math.Min(lat, MaxLatitude), -MaxLatitude)
+	latRad := lat * math.Pi / 180.0
+	y := (1.0 - math.Log(math.Tan(latRad)+1.0/math.Cos(latRad))/math.Pi) / 2.0 * math.Exp2(float64(z))
+	return clampTileCoordinate(int(math.Floor(y)), z)
+}
+
+func clampTileCoordinate(c int, z int) int {
+	max := int(math.Exp2(float64(z))) - 1
+	if c < 0 {
+		return 0
+	}
+	if c > max {
+		return max
+	}
+	return c
+}
+
+// TileRange is a rectangular range of tiles on one zoom level
+type TileRange struct {
+	Z    int `json:"z"`
+	MinX int `json:"min_x"`
+	MinY int `json:"min_y"`
+	MaxX int `json:"max_x"`
+	MaxY int `json:"max_y"`
+}
+
+// NewTileRange returns range of tiles which are affected by the bounding box on the zoom level.
+// Neighbour tiles are included as well, because tiles are rendered with a margin (see BoundingBox.AddMargin).
+func NewTileRange(bbox BoundingBox, z int) TileRange {
+	return TileRange{
+		Z:    z,
+		MinX: clampTileCoordinate(Lon2Tile(bbox.West, z)-1, z),
+		MaxX: clampTileCoordinate(Lon2Tile(bbox.East, z)+1, z),
+		MinY: clampTileCoordinate(Lat2Tile(bbox.North, z)-1, z),
+		MaxY: clampTileCoordinate(Lat2Tile(bbox.South, z)+1, z),
+	}
+}
+
+// Contains returns true if the tile with z/x/y coordinates is inside of the range
+func (r TileRange) Contains(z, x, y int) bool {
+	return r.Z == z && r.MinX <= x && x <= r.MaxX && r.MinY <= y && y <= r.MaxY
+}
@@ -38,3 +38,56 @@ func TestTile_Degrees2Pixels(t *testing.T) {
 	assert.Equal(t, 0, x, "point with (0,0) coords should be exactly in the center of whole world tile")
 	assert.Equal(t, TileSize, y, "point with (0,0) coords should be exactly in the center of whole world tile")
 }
+
+func TestTile_PixelSize(t *testing.T) {
+	tile := NewTile(0, 0, 0)
+	assert.Equal(t, 360.0/TileSize, tile.PixelSize(), "whole world tile covers 360 degrees")
+
+	tile = NewTile(2475, 1280, 12)
+	assert.Equal(t, tile.BoundingBox.East-tile.BoundingBox.West, tile.PixelSize()*TileSize)
+}
+
+func TestTile_MinPixelSize(t *testing.T) {
+	/* Near 60°N a pixel is twice shorter in degrees of latitude than in degrees of longitude */
+	tile := NewTile(Lon2Tile(30, 12), Lat2Tile(60, 12), 12)
+	height := tile.BoundingBox.North - tile.BoundingBox.South
+	assert.True(t, tile.MinPixelSize()*TileSize <= height)
+	assert.InDelta(t, height, tile.MinPixelSize()*TileSize, height*0.001)
+	assert.InDelta(t, tile.PixelSize()/2, tile.MinPixelSize(), tile.PixelSize()*0.01)
+}
+
+func TestTile_MetersPerPixel(t *testing.T) {
+	tile := NewTile(0, 0, 0)
+	assert.InDelta(t, EarthCircumference/TileSize, tile.MetersPerPixel(), 0.001)
+
+	tile = NewTile(2475, 1280, 12)
+	assert.InDelta(t, 21.5, tile.MetersPerPixel(), 0.1)
+	assert.InDelta(t, tile.MetersPerPixelAt(0)/2, tile.MetersPerPixelAt(60), 1e-9, "pixels at 60 degrees are twice shorter than at the equator")
+}
+
+func TestTile_TileY2Lat(t *testing.T) {
+	tile := NewTile(2475, 1280, 12)
+	assert.InDelta(t, tile.BoundingBox.North, tile.TileY2Lat(0), 1e-9)
+	assert.InDelta(t, tile.BoundingBox.South, tile.TileY2Lat(TileSize), 1e-9)
+}
+
+func TestLon2TileAndLat2Tile(t *testing.T) {
+	assert.Equal(t, 2475, Lon2Tile(37.55, 12))
+	assert.Equal(t, 1280, Lat2Tile(55.75, 12))
+	assert.Equal(t, 0, Lon2Tile(-180, 3))
+	assert.Equal(t, 7, Lon2Tile(180, 3), "tile coordinate should not be outside of the world")
+	assert.Equal(t, 0, Lat2Tile(90, 3))
+	assert.Equal(t, 7, Lat2Tile(-90, 3))
+}
+
+func TestNewTileRange(t *testing.T) {
+	tile := NewTile(2475, 1280, 12)
+	r := NewTileRange(tile.BoundingBox, 12)
+	assert.True(t, r.Contains(12, 2475, 1280))
+	assert.True(t, r.Contains(12, 2474, 1279), "neighbour tiles are rendered with margin")
+	assert.False(t, r.Contains(12, 2473, 1280))
+	assert.False(t, r.Contains(11, 2475, 1280))
+
+	r = NewTileRange(BoundingBox{North: 85, South: -85, West: -180, East: 180}, 0)
+	assert.Equal(t, TileRange{Z: 0, MinX: 0, MinY: 0, MaxX: 0, MaxY: 0}, r)
+}
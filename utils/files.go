@@ -13,5 +13,5 @@ func FileExists(name *string) bool {
 
 func IsDirectory(path string) bool {
 	fileInfo, err := os.Stat(path)
-	return fileInfo.IsDir() && err != nil
+	return err == nil && fileInfo.IsDir()
 }
@@ -1,26 +1,11 @@
 package utils
 
 import (
-	"errors"
 	"fmt"
 	"io/ioutil"
-	"net/http"
 	"os"
 )
 
-func GetImgByURL(url string) ([]byte, error) {
-	if resp, err := http.Get(url); err == nil {
-		defer resp.Body.Close()
-		if result, readErr := ioutil.ReadAll(resp.Body); readErr == nil {
-			return result, nil
-		} else {
-			return nil, errors.New("Can't read bytes from image loading response")
-		}
-	} else {
-		return nil, errors.New("Can't load img")
-	}
-}
-
 func SaveImageToFile(path string, content []byte) error {
 	file, err := os.Create(path)
 	if err != nil {